@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AppCredentialRole names a role to delegate to an application credential
+type AppCredentialRole struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// CreateAppCredentialRequest is the body for POST /v3/users/{user_id}/application_credentials
+type CreateAppCredentialRequest struct {
+	ApplicationCredential struct {
+		Name         string              `json:"name"`
+		Description  string              `json:"description,omitempty"`
+		ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+		Roles        []AppCredentialRole `json:"roles,omitempty"`
+		Unrestricted bool                `json:"unrestricted,omitempty"`
+	} `json:"application_credential"`
+}
+
+// AppCredential is an application credential as returned by Keystone. The
+// secret is only present in the create response.
+type AppCredential struct {
+	ID           string              `json:"id"`
+	Name         string              `json:"name"`
+	Description  string              `json:"description"`
+	Secret       string              `json:"secret,omitempty"`
+	ProjectID    string              `json:"project_id"`
+	ExpiresAt    *time.Time          `json:"expires_at"`
+	Roles        []AppCredentialRole `json:"roles"`
+	Unrestricted bool                `json:"unrestricted"`
+}
+
+// CreateAppCredential mints an application credential for userID, scoped to
+// the project of the token used to create it.
+func CreateAppCredential(identityUrl, token, userID string, request CreateAppCredentialRequest) (AppCredential, error) {
+	var wrapper struct {
+		ApplicationCredential AppCredential `json:"application_credential"`
+	}
+
+	url := fmt.Sprintf("%s/users/%s/application_credentials", identityUrl, userID)
+
+	apiResp, err := callPOST(url, token, request)
+	if err != nil {
+		return wrapper.ApplicationCredential, fmt.Errorf("failed to create application credential: %v", err)
+	}
+
+	if apiResp.ResponseCode != 201 {
+		return wrapper.ApplicationCredential, apiResp.fail("create application credential failed")
+	}
+
+	err = json.Unmarshal([]byte(apiResp.Response), &wrapper)
+	if err != nil {
+		return wrapper.ApplicationCredential, fmt.Errorf("error unmarshalling application credential: %v", err)
+	}
+
+	return wrapper.ApplicationCredential, nil
+}
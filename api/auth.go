@@ -1,20 +1,35 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+	"github.com/jessegalley/vhicmd/internal/secrets"
 )
 
 var TokenFile string
 
-// TokenStore structure to store tokens per host
+// TokenFileCipher encrypts the token file at rest.
+type TokenFileCipher interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// TokenCipher, when set, is used to read and write the token file.
+var TokenCipher TokenFileCipher
+
+// TokenStore structure to store tokens per host, domain and project
 type TokenStore struct {
-	Tokens map[string]Token `json:"tokens"` // map[hostname]Token
+	Tokens map[string]Token `json:"tokens"` // map[TokenKey(host, domain, project)]Token
 }
 
 // Token structure to store the token, its expiration, the host, and the compute URL
@@ -22,10 +37,72 @@ type Token struct {
 	Value     string            `json:"value"`
 	ExpiresAt time.Time         `json:"expires_at"`
 	Host      string            `json:"host"`
-	Endpoints map[string]string `json:"endpoints,omitempty"`
+	Endpoints map[string]string `json:"endpoints,omitempty"` // selected with SelectEndpoints
+	Catalog   []CatalogEndpoint `json:"catalog,omitempty"`
+	Domain    string            `json:"domain,omitempty"`
+	DomainID  string            `json:"domain_id,omitempty"`
 	Project   string            `json:"project,omitempty"`
+	ProjectID string            `json:"project_id,omitempty"`
+	Scope     string            `json:"scope,omitempty"` // empty means ScopeProject
+	UserID    string            `json:"user_id,omitempty"`
+}
+
+// SelectEndpoints replaces t.Endpoints with the catalog endpoints for region
+// and iface. Tokens saved before the whole catalog was kept only have the
+// public endpoints, which are used as-is for the default selection.
+func (t *Token) SelectEndpoints(region, iface string) error {
+	if iface == "" {
+		iface = DefaultInterface
+	}
+	if len(t.Catalog) == 0 {
+		if region != "" || iface != DefaultInterface {
+			return fmt.Errorf("cached token for %s has no service catalog; run 'vhicmd auth' again to select a region or interface", t.Host)
+		}
+		return nil
+	}
+	t.Endpoints = SelectEndpoints(t.Catalog, region, iface)
+	if len(t.Endpoints) == 0 {
+		if region != "" {
+			return fmt.Errorf("no '%s' endpoints for region '%s' in the service catalog", iface, region)
+		}
+		return fmt.Errorf("no '%s' endpoints in the service catalog", iface)
+	}
+	return nil
+}
+
+// Token scopes. Domain- and system-scoped tokens are only useful for admin
+// calls such as listing domains.
+const (
+	ScopeProject = "project"
+	ScopeDomain  = "domain"
+	ScopeSystem  = "system"
+)
+
+// ScopeName returns the token's scope, defaulting to ScopeProject.
+func (t Token) ScopeName() string {
+	if t.Scope == "" {
+		return ScopeProject
+	}
+	return t.Scope
 }
 
+// key returns the token store key. Domain and system tokens use a marker in
+// place of the project so they never shadow a project token.
+func (t Token) key() string {
+	switch t.ScopeName() {
+	case ScopeDomain, ScopeSystem:
+		return TokenKey(t.Host, t.Domain, "@"+t.Scope)
+	}
+	return TokenKey(t.Host, t.Domain, t.Project)
+}
+
+// Keystone identity methods supported by vhicmd
+const (
+	AuthMethodPassword      = "password"
+	AuthMethodAppCredential = "application_credential"
+	AuthMethodToken         = "token"
+)
+
 // AuthPayload is used for the authentication request body
 type AuthPayload struct {
 	Auth Auth `json:"auth"`
@@ -34,13 +111,26 @@ type AuthPayload struct {
 // Auth structure for the authentication request
 type Auth struct {
 	Identity Identity `json:"identity"`
-	Scope    Scope    `json:"scope"`
+	Scope    *Scope   `json:"scope,omitempty"`
 }
 
 // Identity structure for the authentication request
 type Identity struct {
-	Methods  []string `json:"methods"`
-	Password Password `json:"password"`
+	Methods               []string                   `json:"methods"`
+	Password              *Password                  `json:"password,omitempty"`
+	ApplicationCredential *ApplicationCredentialAuth `json:"application_credential,omitempty"`
+	Token                 *TokenAuth                 `json:"token,omitempty"`
+}
+
+// ApplicationCredentialAuth structure for the application_credential method
+type ApplicationCredentialAuth struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// TokenAuth structure for the token method
+type TokenAuth struct {
+	ID string `json:"id"`
 }
 
 // Password structure for the authentication request
@@ -61,39 +151,37 @@ type Domain struct {
 	Name string `json:"name,omitempty"`
 }
 
-// Scope structure for the authentication request
+// Scope structure for the authentication request. Exactly one of the
+// fields is set.
 type Scope struct {
-	Project Project `json:"project"`
+	Project *Project     `json:"project,omitempty"`
+	Domain  *Domain      `json:"domain,omitempty"`
+	System  *SystemScope `json:"system,omitempty"`
 }
 
-// Project structure for the authentication request
+// Project structure for the authentication request. A project given by ID
+// needs no domain.
 type Project struct {
-	Name   string `json:"name,omitempty"`
-	Domain Domain `json:"domain"`
+	ID     string  `json:"id,omitempty"`
+	Name   string  `json:"name,omitempty"`
+	Domain *Domain `json:"domain,omitempty"`
+}
+
+// SystemScope structure for a system-scoped authentication request
+type SystemScope struct {
+	All bool `json:"all"`
 }
 
 // Builds the authentication payload
 func newAuthPayload(domain Domain, project, user, password string) AuthPayload {
-	return AuthPayload{
-		Auth: Auth{
-			Identity: Identity{
-				Methods: []string{"password"},
-				Password: Password{
-					User: User{
-						Name:     user,
-						Domain:   domain,
-						Password: password,
-					},
-				},
-			},
-			Scope: Scope{
-				Project: Project{
-					Name:   project,
-					Domain: domain,
-				},
-			},
+	payload := newPasswordPayload(domain, user, password)
+	payload.Auth.Scope = &Scope{
+		Project: &Project{
+			Name:   project,
+			Domain: &domain,
 		},
 	}
+	return payload
 }
 
 // AuthResponse structure for the authentication response
@@ -120,7 +208,14 @@ type AuthResponse struct {
 			ID   string `json:"id"`
 			Name string `json:"name"`
 		} `json:"project"`
-		IsDomain bool `json:"is_domain"`
+		// Domain is set instead of Project for a domain-scoped token, and
+		// System for a system-scoped one
+		Domain struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"domain"`
+		System   map[string]interface{} `json:"system"`
+		IsDomain bool                   `json:"is_domain"`
 		Roles    []struct {
 			ID   string `json:"id"`
 			Name string `json:"name"`
@@ -140,89 +235,305 @@ type AuthResponse struct {
 	} `json:"token"`
 }
 
-// SaveToken saves or updates a token in the token store
-func SaveToken(host, project string, token string, expiresAt time.Time, endpoints map[string]string) error {
-	store, err := loadTokenStore()
-	if err != nil {
-		store = TokenStore{Tokens: make(map[string]Token)}
-	}
+// TokenKey returns the token store key for a host, domain and project.
+func TokenKey(host, domain, project string) string {
+	return strings.Join([]string{host, domain, project}, "|")
+}
 
-	store.Tokens[host] = Token{
+// SaveToken saves or updates a token in the token store
+func SaveToken(host, domain, project string, token string, expiresAt time.Time, endpoints map[string]string) error {
+	return saveToken(Token{
 		Value:     token,
 		ExpiresAt: expiresAt,
 		Host:      host,
 		Endpoints: endpoints,
+		Domain:    domain,
 		Project:   project,
-	}
+	})
+}
 
-	data, err := json.MarshalIndent(store, "", "  ")
+func saveToken(t Token) error {
+	store, err := loadTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to marshal token store: %v", err)
-	}
-	if err := os.MkdirAll(filepath.Dir(TokenFile), 0700); err != nil {
-		return fmt.Errorf("failed to create token directory: %v", err)
+		store = TokenStore{Tokens: make(map[string]Token)}
 	}
 
-	return os.WriteFile(TokenFile, data, 0600)
+	store.Tokens[t.key()] = t
+
+	return writeTokenStore(store)
 }
 
-// LoadToken loads the token for a specific host
-func LoadTokenStruct(host string) (Token, error) {
+// ErrTokenExpired is wrapped by the errors LoadTokenStruct and
+// LoadScopedToken return when the cached token has expired.
+var ErrTokenExpired = errors.New("expired")
+
+// LoadTokenStruct loads the project-scoped token for a host, domain and
+// project, each of which may be a name or an ID. An empty project picks the
+// most recently issued token for the host. Tokens migrated from the old
+// per-host format have no domain and match any domain.
+func LoadTokenStruct(host, domain, project string) (Token, error) {
 	var t Token
 
 	store, err := loadTokenStore()
 	if err != nil {
 		return t, err
 	}
-	tokenObj, exists := store.Tokens[host]
+
+	tokenObj, exists := store.Tokens[TokenKey(host, domain, project)]
 	if !exists {
+		for _, candidate := range store.Tokens {
+			if candidate.Host != host || candidate.ScopeName() != ScopeProject {
+				continue
+			}
+			if domain != "" && candidate.Domain != "" && candidate.Domain != domain && candidate.DomainID != domain {
+				continue
+			}
+			if project != "" && candidate.Project != project && candidate.ProjectID != project {
+				continue
+			}
+			if !exists || candidate.ExpiresAt.After(tokenObj.ExpiresAt) {
+				tokenObj = candidate
+				exists = true
+			}
+		}
+	}
+	if !exists {
+		if project != "" {
+			return t, fmt.Errorf("no token found for host %s, project %s", host, project)
+		}
 		return t, fmt.Errorf("no token found for host %s", host)
 	}
 
 	// Check expiration
 	if time.Now().After(tokenObj.ExpiresAt) {
-		return t, fmt.Errorf("token for %s is expired", host)
+		return t, fmt.Errorf("token for %s is %w", host, ErrTokenExpired)
 	}
 
 	return tokenObj, nil
 }
 
+// LoadScopedToken loads the newest domain- or system-scoped token for host.
+// For ScopeDomain, domain (a name or ID) picks the domain; empty matches any.
+func LoadScopedToken(host, domain, scope string) (Token, error) {
+	var tokenObj Token
+
+	store, err := loadTokenStore()
+	if err != nil {
+		return tokenObj, err
+	}
+
+	exists := false
+	for _, candidate := range store.Tokens {
+		if candidate.Host != host || candidate.Scope != scope {
+			continue
+		}
+		if scope == ScopeDomain && domain != "" && candidate.Domain != domain && candidate.DomainID != domain {
+			continue
+		}
+		if !exists || candidate.ExpiresAt.After(tokenObj.ExpiresAt) {
+			tokenObj = candidate
+			exists = true
+		}
+	}
+	if !exists {
+		return tokenObj, fmt.Errorf("no %s-scoped token found for host %s", scope, host)
+	}
+	if time.Now().After(tokenObj.ExpiresAt) {
+		return Token{}, fmt.Errorf("%s-scoped token for %s is %w", scope, host, ErrTokenExpired)
+	}
+
+	return tokenObj, nil
+}
+
+// ListTokens returns every cached token, sorted by host, domain and project.
+func ListTokens() ([]Token, error) {
+	store, err := loadTokenStore()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	tokens := make([]Token, 0, len(store.Tokens))
+	for _, t := range store.Tokens {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].key() < tokens[j].key()
+	})
+	return tokens, nil
+}
+
+// DeleteToken removes the cached project token for a host, domain and project.
+func DeleteToken(host, domain, project string) error {
+	return RemoveToken(Token{Host: host, Domain: domain, Project: project})
+}
+
+// RemoveToken removes t, as returned by ListTokens, from the token store.
+func RemoveToken(t Token) error {
+	store, err := loadTokenStore()
+	if err != nil {
+		return err
+	}
+
+	key := t.key()
+	if _, ok := store.Tokens[key]; !ok {
+		return fmt.Errorf("no token found for host %s, domain %s, project %s", t.Host, t.Domain, t.Project)
+	}
+	delete(store.Tokens, key)
+
+	return writeTokenStore(store)
+}
+
+// RewriteTokenFile reads and rewrites the token file, e.g. to encrypt it
+// after TokenCipher is set. A missing file is not an error.
+func RewriteTokenFile() error {
+	store, err := loadTokenStore()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return writeTokenStore(store)
+}
+
 func loadTokenStore() (TokenStore, error) {
 	var store TokenStore
 	data, err := os.ReadFile(TokenFile)
 	if err != nil {
-		return store, fmt.Errorf("failed to read token file: %v", err)
+		return store, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	if TokenCipher != nil {
+		data, err = TokenCipher.Open(data)
+		if err != nil {
+			return store, fmt.Errorf("failed to decrypt token file: %v", err)
+		}
+	} else if secrets.IsEncrypted(string(data)) {
+		return store, fmt.Errorf("token file is encrypted; set secret_backend to 'passphrase'")
 	}
 
 	err = json.Unmarshal(data, &store)
 	if err != nil {
 		return store, fmt.Errorf("failed to unmarshal token data: %v", err)
 	}
+	if store.Tokens == nil {
+		store.Tokens = make(map[string]Token)
+	}
+
+	if migrateTokenStore(&store) {
+		if err := writeTokenStore(store); err != nil {
+			return store, fmt.Errorf("failed to migrate token file: %v", err)
+		}
+	}
 
 	return store, nil
 }
 
+// migrateTokenStore re-keys tokens saved by older versions, which kept a
+// single token per hostname. It reports whether anything changed.
+func migrateTokenStore(store *TokenStore) bool {
+	migrated := false
+	for key, t := range store.Tokens {
+		if strings.Contains(key, "|") {
+			continue
+		}
+		if t.Host == "" {
+			t.Host = key
+		}
+		delete(store.Tokens, key)
+		store.Tokens[t.key()] = t
+		migrated = true
+	}
+	return migrated
+}
+
+func writeTokenStore(store TokenStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token store: %v", err)
+	}
+	if TokenCipher != nil {
+		data, err = TokenCipher.Seal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt token file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(TokenFile), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %v", err)
+	}
+
+	return os.WriteFile(TokenFile, data, 0600)
+}
+
 // Authenticate uses domain/project names, calls the auth token API, and returns the token on success.
 func Authenticate(host, domain, project, username, password string, force bool) (string, error) {
 	// Only check existing token if not forcing reauth
 	if !force {
-		existingToken, err := LoadTokenStruct(host)
-		if err == nil && project == existingToken.Project {
+		existingToken, err := LoadTokenStruct(host, domain, project)
+		if err == nil {
 			fmt.Printf("Using existing token for %s, project %s\n", host, project)
 			return existingToken.Value, nil
 		}
 	}
 
 	// Not found, expired, or user wants a different project -> do a fresh authentication
-	url := fmt.Sprintf("https://%s:5000/v3/auth/tokens", host)
 	payload := newAuthPayload(Domain{Name: domain}, project, username, password)
+	return issueToken(host, payload, Token{Domain: domain, Project: project})
+}
+
+// AuthenticateAppCredential authenticates with a Keystone application
+// credential. The credential is bound to a project, so no scope is sent;
+// the token is saved under the project and domain Keystone reports.
+func AuthenticateAppCredential(host, credentialID, secret string) (string, error) {
+	payload := AuthPayload{
+		Auth: Auth{
+			Identity: Identity{
+				Methods: []string{AuthMethodAppCredential},
+				ApplicationCredential: &ApplicationCredentialAuth{
+					ID:     credentialID,
+					Secret: secret,
+				},
+			},
+		},
+	}
+	return issueToken(host, payload, Token{})
+}
+
+// AuthenticateWithToken exchanges an existing token for a new one scoped to
+// the given project, e.g. to switch projects without a password.
+func AuthenticateWithToken(host, domain, project, token string) (string, error) {
+	payload := AuthPayload{
+		Auth: Auth{
+			Identity: Identity{
+				Methods: []string{AuthMethodToken},
+				Token:   &TokenAuth{ID: token},
+			},
+			Scope: &Scope{
+				Project: &Project{
+					Name:   project,
+					Domain: &Domain{Name: domain},
+				},
+			},
+		},
+	}
+	return issueToken(host, payload, Token{Domain: domain, Project: project})
+}
+
+// issueToken posts payload to Keystone, checks the token is scoped the way
+// want asks for and saves it. Names and IDs left empty in want are filled in
+// from the response.
+func issueToken(host string, payload AuthPayload, want Token) (string, error) {
+	url := fmt.Sprintf("https://%s:5000/v3/auth/tokens", host)
 	apiResp, err := callPOST(url, "", payload)
 	if err != nil {
 		return "", fmt.Errorf("authentication request failed: %v", err)
 	}
 
 	if apiResp.ResponseCode != 201 {
-		return "", fmt.Errorf("authentication failed: %v", apiResp.Response)
+		return "", apiResp.fail("authentication failed")
 	}
 	if apiResp.TokenHeader == "" {
 		return "", fmt.Errorf("no token found in the response")
@@ -234,95 +545,283 @@ func Authenticate(host, domain, project, username, password string, force bool)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse auth response: %v", err)
 	}
-	expiresAt := authResponse.Token.ExpiresAt
 
-	// Extract "public" endpoints we care about from the catalog
-	endpoints := make(map[string]string)
-	for _, svc := range authResponse.Token.Catalog {
-		for _, ep := range svc.Endpoints {
-			if ep.Interface == "public" {
-				endpoints[svc.Type] = ep.URL
-			}
-		}
+	t := want
+	if err := applyAuthScope(&t, authResponse); err != nil {
+		return "", err
 	}
+	t.Value = apiResp.TokenHeader
+	t.ExpiresAt = authResponse.Token.ExpiresAt
+	t.Host = host
+	t.Catalog = catalogEndpoints(authResponse)
+	t.Endpoints = SelectEndpoints(t.Catalog, "", DefaultInterface)
+	t.UserID = authResponse.Token.User.ID
 
 	// Save token + endpoints
-	err = SaveToken(host, project, apiResp.TokenHeader, expiresAt, endpoints)
-	if err != nil {
+	if err := saveToken(t); err != nil {
 		return "", fmt.Errorf("failed to save token: %v", err)
 	}
 
 	return apiResp.TokenHeader, nil
 }
 
-// AuthenticateById uses domain ID instead of name
-// Broken currently, need to fix - project ID not getting parsed
-// TODO: Fix this
-func AuthenticateById(host, domainID, project, username, password string) (string, error) {
-	// Try existing token first
-	existingToken, err := LoadTokenStruct(host)
-	if err == nil {
-		return existingToken.Project, nil
+// applyAuthScope checks that Keystone granted the scope t asks for and
+// records the granted project or domain names and IDs in t. Keystone
+// silently returns an unscoped token when the user has no role on the
+// requested target, so that is an error too.
+func applyAuthScope(t *Token, resp AuthResponse) error {
+	granted := ""
+	switch {
+	case resp.Token.System != nil:
+		granted = ScopeSystem
+	case resp.Token.Domain.ID != "":
+		granted = ScopeDomain
+	case resp.Token.Project.ID != "":
+		granted = ScopeProject
+	default:
+		return fmt.Errorf("keystone returned an unscoped token; check the user has a role on the requested %s", t.ScopeName())
+	}
+	if granted != t.ScopeName() {
+		return fmt.Errorf("requested a %s-scoped token but keystone returned a %s-scoped one", t.ScopeName(), granted)
+	}
+
+	switch granted {
+	case ScopeProject:
+		p := resp.Token.Project
+		if err := matchScope("project", t.ProjectID, t.Project, p.ID, p.Name); err != nil {
+			return err
+		}
+		if err := matchScope("domain", t.DomainID, t.Domain, p.Domain.ID, p.Domain.Name); err != nil {
+			return err
+		}
+		t.ProjectID = p.ID
+		if t.Project == "" {
+			t.Project = p.Name
+		}
+		t.DomainID = p.Domain.ID
+		if t.Domain == "" {
+			t.Domain = p.Domain.Name
+		}
+	case ScopeDomain:
+		d := resp.Token.Domain
+		if err := matchScope("domain", t.DomainID, t.Domain, d.ID, d.Name); err != nil {
+			return err
+		}
+		t.DomainID = d.ID
+		if t.Domain == "" {
+			t.Domain = d.Name
+		}
+		t.Project, t.ProjectID = "", ""
+	case ScopeSystem:
+		if all, _ := resp.Token.System["all"].(bool); !all {
+			return fmt.Errorf("keystone returned a system-scoped token without 'all'")
+		}
+		t.Domain, t.DomainID, t.Project, t.ProjectID = "", "", "", ""
 	}
+	return nil
+}
 
-	url := fmt.Sprintf("https://%s:5000/v3/auth/tokens", host)
-	payload := AuthPayload{
-		Auth: Auth{
-			Identity: Identity{
-				Methods: []string{"password"},
-				Password: Password{
-					User: User{
-						Name:     username,
-						Domain:   Domain{ID: domainID},
-						Password: password,
-					},
-				},
-			},
-			Scope: Scope{
-				Project: Project{
-					Name:   project,
-					Domain: Domain{ID: domainID},
-				},
-			},
-		},
+// matchScope compares a requested ID and name, either of which may be
+// empty, with those Keystone returned. Names are compared case-insensitively
+// as Keystone does.
+func matchScope(kind, wantID, wantName, gotID, gotName string) error {
+	if wantID != "" && wantID != gotID {
+		return fmt.Errorf("token is scoped to %s %s (%s), not the requested %s", kind, gotName, gotID, wantID)
 	}
+	if wantName != "" && !strings.EqualFold(wantName, gotName) {
+		return fmt.Errorf("token is scoped to %s %s (%s), not the requested %s", kind, gotName, gotID, wantName)
+	}
+	return nil
+}
 
-	apiResp, err := callPOST(url, "", payload)
-	if err != nil {
-		return "", fmt.Errorf("authentication request failed: %v", err)
+// catalogEndpoints flattens the service catalog in the auth response
+func catalogEndpoints(authResponse AuthResponse) []CatalogEndpoint {
+	var endpoints []CatalogEndpoint
+	for _, svc := range authResponse.Token.Catalog {
+		for _, ep := range svc.Endpoints {
+			endpoints = append(endpoints, CatalogEndpoint{
+				Type:      svc.Type,
+				Name:      svc.Name,
+				Interface: ep.Interface,
+				Region:    ep.Region,
+				RegionID:  ep.RegionID,
+				URL:       ep.URL,
+			})
+		}
 	}
-	if apiResp.ResponseCode != 201 {
-		return "", fmt.Errorf("authentication failed: %v", apiResp.Response)
+	return endpoints
+}
+
+// Credentials are the saved login details used to refresh a token that
+// expires in the middle of a command.
+type Credentials struct {
+	Host     string
+	Method   string // AuthMethodPassword (default) or AuthMethodAppCredential
+	Domain   string
+	Project  string
+	Username string
+	Password string
+
+	AppCredentialID     string
+	AppCredentialSecret string
+
+	// Secret, if set, supplies Password or AppCredentialSecret on demand
+	// (keyed by config name), e.g. from an encrypted config or a
+	// credential helper, so nothing is decrypted unless reauth is needed.
+	Secret func(key string) (string, error)
+}
+
+// CanReauth reports whether creds hold enough to get a new token unattended.
+func (c Credentials) CanReauth() bool {
+	switch c.Method {
+	case AuthMethodAppCredential:
+		return c.AppCredentialID != "" && (c.AppCredentialSecret != "" || c.Secret != nil)
+	case "", AuthMethodPassword:
+		return c.Domain != "" && c.Project != "" && c.Username != "" && (c.Password != "" || c.Secret != nil)
 	}
-	if apiResp.TokenHeader == "" {
-		return "", fmt.Errorf("no token found in the response")
+	return false
+}
+
+// secret returns value, or looks key up through c.Secret if value is empty.
+func (c Credentials) secret(key, value string) (string, error) {
+	if value != "" || c.Secret == nil {
+		return value, nil
 	}
+	return c.Secret(key)
+}
 
-	// Parse the auth response
-	var authResponse AuthResponse
-	err = json.Unmarshal([]byte(apiResp.Response), &authResponse)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse auth response: %v", err)
+// Reauthenticate gets and saves a fresh token using creds.
+func (c Credentials) Reauthenticate() (string, error) {
+	switch c.Method {
+	case AuthMethodAppCredential:
+		secret, err := c.secret("application_credential_secret", c.AppCredentialSecret)
+		if err != nil {
+			return "", err
+		}
+		return AuthenticateAppCredential(c.Host, c.AppCredentialID, secret)
+	case "", AuthMethodPassword:
+		password, err := c.secret("password", c.Password)
+		if err != nil {
+			return "", err
+		}
+		return Authenticate(c.Host, c.Domain, c.Project, c.Username, password, true)
 	}
-	expiresAt := authResponse.Token.ExpiresAt
+	return "", fmt.Errorf("auth method '%s' cannot reauthenticate unattended", c.Method)
+}
 
-	// Extract "public" endpoints
-	endpoints := make(map[string]string)
-	for _, svc := range authResponse.Token.Catalog {
-		for _, ep := range svc.Endpoints {
-			if ep.Interface == "public" {
-				endpoints[svc.Type] = ep.URL
-			}
+// ReauthenticateAs gets and saves a fresh token with the same scope as
+// stale, so a replayed request keeps the privileges it was made with.
+// Domain and system tokens need password credentials; a project token is
+// only replaced if it is for the project creds log in to.
+func (c Credentials) ReauthenticateAs(stale Token) (string, error) {
+	switch stale.ScopeName() {
+	case ScopeProject:
+		if c.Method != AuthMethodAppCredential && (stale.Project != "" || stale.ProjectID != "") &&
+			stale.Project != c.Project && stale.ProjectID != c.Project {
+			return "", fmt.Errorf("token is for project '%s', not '%s'", stale.Project, c.Project)
+		}
+		return c.Reauthenticate()
+	case ScopeDomain, ScopeSystem:
+		if c.Method != "" && c.Method != AuthMethodPassword {
+			return "", fmt.Errorf("auth method '%s' cannot issue %s-scoped tokens", c.Method, stale.Scope)
+		}
+		password, err := c.secret("password", c.Password)
+		if err != nil {
+			return "", err
+		}
+		if stale.Scope == ScopeSystem {
+			return AuthenticateSystem(c.Host, Domain{Name: c.Domain}, c.Username, password)
+		}
+		domain := Domain{ID: stale.DomainID}
+		if domain.ID == "" {
+			domain.Name = stale.Domain
 		}
+		return AuthenticateDomain(c.Host, Domain{Name: c.Domain}, c.Username, password, domain)
 	}
+	return "", fmt.Errorf("unknown token scope '%s'", stale.Scope)
+}
 
-	// Save
-	err = SaveToken(host, project, apiResp.TokenHeader, expiresAt, endpoints)
+// findToken returns the cached token whose value is value.
+func findToken(value string) (Token, bool) {
+	store, err := loadTokenStore()
 	if err != nil {
-		return "", fmt.Errorf("failed to save token: %v", err)
+		return Token{}, false
+	}
+	for _, t := range store.Tokens {
+		if t.Value == value {
+			return t, true
+		}
 	}
+	return Token{}, false
+}
 
-	return apiResp.TokenHeader, nil
+// EnableTokenRefresh makes API calls that are rejected with 401 re-authenticate
+// using creds, save the new token with SaveToken and replay the request once.
+// The new token has the scope of the rejected one; tokens that are not in
+// the cache, so whose scope is unknown, are not refreshed.
+func EnableTokenRefresh(creds Credentials) {
+	httpclient.Default.SetTokenRefresher(func(ctx context.Context, stale string) (string, error) {
+		t, ok := findToken(stale)
+		if !ok {
+			return "", fmt.Errorf("reauth for '%s' failed: the rejected token is not in the token cache", creds.Host)
+		}
+		token, err := creds.ReauthenticateAs(t)
+		if err != nil {
+			return "", fmt.Errorf("reauth for '%s' failed: %v", creds.Host, err)
+		}
+		return token, nil
+	})
+}
+
+// AuthenticateById logs in a user of the domain with ID domainID and scopes
+// the token to the project with ID projectID. The token is saved under the
+// project and domain names Keystone reports, along with their IDs.
+func AuthenticateById(host, domainID, projectID, username, password string) (string, error) {
+	// Try existing token first
+	existingToken, err := LoadTokenStruct(host, domainID, projectID)
+	if err == nil && existingToken.ProjectID == projectID {
+		fmt.Printf("Using existing token for %s, project %s\n", host, existingToken.Project)
+		return existingToken.Value, nil
+	}
+
+	payload := newPasswordPayload(Domain{ID: domainID}, username, password)
+	payload.Auth.Scope = &Scope{Project: &Project{ID: projectID}}
+	return issueToken(host, payload, Token{ProjectID: projectID})
+}
+
+// AuthenticateDomain gets a token scoped to domain rather than a project,
+// for admin calls such as listing domains. userDomain is the domain the
+// user belongs to; either may be given by name or ID.
+func AuthenticateDomain(host string, userDomain Domain, username, password string, domain Domain) (string, error) {
+	payload := newPasswordPayload(userDomain, username, password)
+	payload.Auth.Scope = &Scope{Domain: &domain}
+	return issueToken(host, payload, Token{Scope: ScopeDomain, Domain: domain.Name, DomainID: domain.ID})
+}
+
+// AuthenticateSystem gets a system-scoped token, which cloud admins need
+// for some identity calls.
+func AuthenticateSystem(host string, userDomain Domain, username, password string) (string, error) {
+	payload := newPasswordPayload(userDomain, username, password)
+	payload.Auth.Scope = &Scope{System: &SystemScope{All: true}}
+	return issueToken(host, payload, Token{Scope: ScopeSystem})
+}
+
+// newPasswordPayload builds a password authentication payload without a scope
+func newPasswordPayload(domain Domain, user, password string) AuthPayload {
+	return AuthPayload{
+		Auth: Auth{
+			Identity: Identity{
+				Methods: []string{AuthMethodPassword},
+				Password: &Password{
+					User: User{
+						Name:     user,
+						Domain:   domain,
+						Password: password,
+					},
+				},
+			},
+		},
+	}
 }
 
 // GetTokenFilePath returns the path to the token file
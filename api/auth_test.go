@@ -0,0 +1,204 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenStoreMigratesHostKeys(t *testing.T) {
+	TokenFile = filepath.Join(t.TempDir(), ".vhicmd.token")
+	defer func() { TokenFile = "" }()
+
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	old := `{"tokens":{"vhi.example":{"value":"old","expires_at":"` + expires + `","host":"vhi.example","project":"alpha"}}}`
+	if err := os.WriteFile(TokenFile, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrated tokens have no domain and should match any domain
+	tok, err := LoadTokenStruct("vhi.example", "mydomain", "alpha")
+	if err != nil {
+		t.Fatalf("LoadTokenStruct: %v", err)
+	}
+	if tok.Value != "old" {
+		t.Errorf("got token %q; want %q", tok.Value, "old")
+	}
+
+	if err := SaveToken("vhi.example", "mydomain", "beta", "new", time.Now().Add(2*time.Hour), nil); err != nil {
+		t.Fatalf("SaveToken: %v", err)
+	}
+
+	tokens, err := ListTokens()
+	if err != nil {
+		t.Fatalf("ListTokens: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens; want 2", len(tokens))
+	}
+
+	tok, err = LoadTokenStruct("vhi.example", "mydomain", "alpha")
+	if err != nil || tok.Value != "old" {
+		t.Errorf("project alpha: got %q, %v; want %q", tok.Value, err, "old")
+	}
+	tok, err = LoadTokenStruct("vhi.example", "mydomain", "beta")
+	if err != nil || tok.Value != "new" {
+		t.Errorf("project beta: got %q, %v; want %q", tok.Value, err, "new")
+	}
+
+	// Without a project the newest token for the host wins
+	tok, err = LoadTokenStruct("vhi.example", "mydomain", "")
+	if err != nil || tok.Value != "new" {
+		t.Errorf("no project: got %q, %v; want %q", tok.Value, err, "new")
+	}
+
+	if err := DeleteToken("vhi.example", "", "alpha"); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	if _, err := LoadTokenStruct("vhi.example", "mydomain", "alpha"); err == nil {
+		t.Error("expected no token for alpha after delete")
+	}
+}
+
+func TestApplyAuthScope(t *testing.T) {
+	var resp AuthResponse
+	resp.Token.Project.ID = "p-123"
+	resp.Token.Project.Name = "Alpha"
+	resp.Token.Project.Domain.ID = "d-456"
+	resp.Token.Project.Domain.Name = "mydomain"
+
+	tok := Token{ProjectID: "p-123"}
+	if err := applyAuthScope(&tok, resp); err != nil {
+		t.Fatalf("applyAuthScope: %v", err)
+	}
+	if tok.Project != "Alpha" || tok.Domain != "mydomain" || tok.DomainID != "d-456" {
+		t.Errorf("got project %q domain %q (%q); want Alpha mydomain (d-456)", tok.Project, tok.Domain, tok.DomainID)
+	}
+
+	// Names match case-insensitively and the requested spelling is kept
+	tok = Token{Domain: "MyDomain", Project: "alpha"}
+	if err := applyAuthScope(&tok, resp); err != nil {
+		t.Fatalf("applyAuthScope by name: %v", err)
+	}
+	if tok.Project != "alpha" || tok.ProjectID != "p-123" {
+		t.Errorf("got project %q (%q); want alpha (p-123)", tok.Project, tok.ProjectID)
+	}
+
+	tok = Token{ProjectID: "p-999"}
+	if err := applyAuthScope(&tok, resp); err == nil {
+		t.Error("expected an error for a token scoped to another project")
+	}
+
+	tok = Token{Scope: ScopeDomain, Domain: "mydomain"}
+	if err := applyAuthScope(&tok, resp); err == nil {
+		t.Error("expected an error for a project token when a domain scope was requested")
+	}
+
+	if err := applyAuthScope(&Token{}, AuthResponse{}); err == nil {
+		t.Error("expected an error for an unscoped token")
+	}
+
+	var sys AuthResponse
+	sys.Token.System = map[string]interface{}{"all": true}
+	tok = Token{Scope: ScopeSystem, Domain: "Default"}
+	if err := applyAuthScope(&tok, sys); err != nil {
+		t.Fatalf("applyAuthScope system: %v", err)
+	}
+	if tok.Domain != "" {
+		t.Errorf("system token kept domain %q", tok.Domain)
+	}
+}
+
+func TestScopedTokensDoNotShadowProjectTokens(t *testing.T) {
+	TokenFile = filepath.Join(t.TempDir(), ".vhicmd.token")
+	defer func() { TokenFile = "" }()
+
+	expires := time.Now().Add(time.Hour)
+	tokens := []Token{
+		{Value: "proj", Host: "vhi.example", Domain: "mydomain", DomainID: "d-1", Project: "alpha", ProjectID: "p-1", ExpiresAt: expires},
+		{Value: "dom", Host: "vhi.example", Domain: "mydomain", DomainID: "d-1", Scope: ScopeDomain, ExpiresAt: expires.Add(time.Hour)},
+		{Value: "sys", Host: "vhi.example", Scope: ScopeSystem, ExpiresAt: expires.Add(time.Hour)},
+	}
+	for _, tok := range tokens {
+		if err := saveToken(tok); err != nil {
+			t.Fatalf("saveToken: %v", err)
+		}
+	}
+
+	tok, err := LoadTokenStruct("vhi.example", "mydomain", "")
+	if err != nil || tok.Value != "proj" {
+		t.Errorf("no project: got %q, %v; want %q", tok.Value, err, "proj")
+	}
+	tok, err = LoadTokenStruct("vhi.example", "d-1", "p-1")
+	if err != nil || tok.Value != "proj" {
+		t.Errorf("by ID: got %q, %v; want %q", tok.Value, err, "proj")
+	}
+	tok, err = LoadScopedToken("vhi.example", "d-1", ScopeDomain)
+	if err != nil || tok.Value != "dom" {
+		t.Errorf("domain scope: got %q, %v; want %q", tok.Value, err, "dom")
+	}
+	tok, err = LoadScopedToken("vhi.example", "", ScopeSystem)
+	if err != nil || tok.Value != "sys" {
+		t.Errorf("system scope: got %q, %v; want %q", tok.Value, err, "sys")
+	}
+
+	if err := RemoveToken(tokens[2]); err != nil {
+		t.Fatalf("RemoveToken: %v", err)
+	}
+	if _, err := LoadScopedToken("vhi.example", "", ScopeSystem); err == nil {
+		t.Error("expected no system token after remove")
+	}
+}
+
+func TestExpiredTokenError(t *testing.T) {
+	TokenFile = filepath.Join(t.TempDir(), ".vhicmd.token")
+	defer func() { TokenFile = "" }()
+
+	stale := time.Now().Add(-time.Minute)
+	for _, tok := range []Token{
+		{Value: "proj", Host: "vhi.example", Domain: "mydomain", Project: "alpha", ExpiresAt: stale},
+		{Value: "dom", Host: "vhi.example", Domain: "mydomain", Scope: ScopeDomain, ExpiresAt: stale},
+	} {
+		if err := saveToken(tok); err != nil {
+			t.Fatalf("saveToken: %v", err)
+		}
+	}
+
+	if _, err := LoadTokenStruct("vhi.example", "mydomain", "alpha"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("project token: got %v, want ErrTokenExpired", err)
+	}
+	if _, err := LoadScopedToken("vhi.example", "mydomain", ScopeDomain); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("domain token: got %v, want ErrTokenExpired", err)
+	}
+	if _, err := LoadTokenStruct("vhi.example", "mydomain", "beta"); errors.Is(err, ErrTokenExpired) {
+		t.Error("a missing token is reported as expired")
+	}
+}
+
+func TestReauthenticateAsKeepsScope(t *testing.T) {
+	TokenFile = filepath.Join(t.TempDir(), ".vhicmd.token")
+	defer func() { TokenFile = "" }()
+
+	dom := Token{Value: "dom", Host: "vhi.example", Domain: "mydomain", Scope: ScopeDomain, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := saveToken(dom); err != nil {
+		t.Fatalf("saveToken: %v", err)
+	}
+	if got, ok := findToken("dom"); !ok || got.ScopeName() != ScopeDomain {
+		t.Errorf("findToken: got %+v, %v", got, ok)
+	}
+	if _, ok := findToken("other"); ok {
+		t.Error("findToken found a token that is not cached")
+	}
+
+	// Neither of these may fall back to a project-scoped token
+	appCred := Credentials{Host: "vhi.example", Method: AuthMethodAppCredential, AppCredentialID: "ac", AppCredentialSecret: "s"}
+	if _, err := appCred.ReauthenticateAs(dom); err == nil {
+		t.Error("expected an application credential to refuse a domain-scoped token")
+	}
+	password := Credentials{Host: "vhi.example", Domain: "mydomain", Project: "alpha", Username: "u", Password: "p"}
+	if _, err := password.ReauthenticateAs(Token{Host: "vhi.example", Project: "beta"}); err == nil {
+		t.Error("expected a token for another project to be refused")
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // CatalogResponse represents the response from the /v3/auth/catalog endpoint.
@@ -12,12 +13,69 @@ type CatalogResponse struct {
 		Name      string `json:"name"`
 		Endpoints []struct {
 			Region    string `json:"region"`
+			RegionID  string `json:"region_id"`
 			Interface string `json:"interface"`
 			URL       string `json:"url"`
 		} `json:"endpoints"`
 	} `json:"catalog"`
 }
 
+// DefaultInterface is the endpoint interface used when none is configured
+const DefaultInterface = "public"
+
+// CatalogEndpoint is one endpoint of one service in the service catalog
+type CatalogEndpoint struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Interface string `json:"interface"`
+	Region    string `json:"region,omitempty"`
+	RegionID  string `json:"region_id,omitempty"`
+	URL       string `json:"url"`
+}
+
+// Endpoints flattens the catalog into one entry per endpoint
+func (r CatalogResponse) Endpoints() []CatalogEndpoint {
+	var endpoints []CatalogEndpoint
+	for _, svc := range r.Catalog {
+		for _, ep := range svc.Endpoints {
+			endpoints = append(endpoints, CatalogEndpoint{
+				Type:      svc.Type,
+				Name:      svc.Name,
+				Interface: ep.Interface,
+				Region:    ep.Region,
+				RegionID:  ep.RegionID,
+				URL:       ep.URL,
+			})
+		}
+	}
+	return endpoints
+}
+
+// InRegion reports whether the endpoint belongs to region, matched against
+// both the region ID and the legacy region name. An empty region matches any.
+func (e CatalogEndpoint) InRegion(region string) bool {
+	return region == "" || e.RegionID == region || e.Region == region
+}
+
+// SelectEndpoints picks one URL per service type from catalog for the given
+// region and interface (default public). With no region, the first matching
+// endpoint in catalog order wins.
+func SelectEndpoints(catalog []CatalogEndpoint, region, iface string) map[string]string {
+	if iface == "" {
+		iface = DefaultInterface
+	}
+	endpoints := make(map[string]string)
+	for _, ep := range catalog {
+		if !strings.EqualFold(ep.Interface, iface) || !ep.InRegion(region) {
+			continue
+		}
+		if _, ok := endpoints[ep.Type]; !ok {
+			endpoints[ep.Type] = ep.URL
+		}
+	}
+	return endpoints
+}
+
 // GetCatalog fetches the service catalog from the Identity API.
 func GetCatalog(host, token string) (CatalogResponse, error) {
 	var result CatalogResponse
@@ -31,7 +89,7 @@ func GetCatalog(host, token string) (CatalogResponse, error) {
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("service catalog request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("service catalog request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
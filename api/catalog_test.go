@@ -0,0 +1,43 @@
+package api
+
+import "testing"
+
+func TestSelectEndpoints(t *testing.T) {
+	catalog := []CatalogEndpoint{
+		{Type: "compute", Interface: "public", RegionID: "east", URL: "https://east.example/compute"},
+		{Type: "compute", Interface: "internal", RegionID: "east", URL: "http://10.0.0.1/compute"},
+		{Type: "compute", Interface: "public", RegionID: "west", URL: "https://west.example/compute"},
+		{Type: "image", Interface: "public", Region: "west", URL: "https://west.example/image"},
+	}
+
+	tests := []struct {
+		region, iface string
+		want          map[string]string
+	}{
+		{"", "", map[string]string{"compute": "https://east.example/compute", "image": "https://west.example/image"}},
+		{"west", "public", map[string]string{"compute": "https://west.example/compute", "image": "https://west.example/image"}},
+		{"east", "INTERNAL", map[string]string{"compute": "http://10.0.0.1/compute"}},
+		{"north", "public", map[string]string{}},
+	}
+	for _, tt := range tests {
+		got := SelectEndpoints(catalog, tt.region, tt.iface)
+		if len(got) != len(tt.want) {
+			t.Errorf("SelectEndpoints(%q, %q) = %v; want %v", tt.region, tt.iface, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("SelectEndpoints(%q, %q)[%s] = %q; want %q", tt.region, tt.iface, k, got[k], v)
+			}
+		}
+	}
+
+	// Old tokens without a catalog only support the default selection
+	tok := Token{Host: "vhi.example", Endpoints: map[string]string{"compute": "https://vhi.example/compute"}}
+	if err := tok.SelectEndpoints("", ""); err != nil || tok.Endpoints["compute"] == "" {
+		t.Errorf("default selection on old token: %v, %v", tok.Endpoints, err)
+	}
+	if err := tok.SelectEndpoints("", "internal"); err == nil {
+		t.Error("expected an error selecting internal endpoints without a catalog")
+	}
+}
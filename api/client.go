@@ -0,0 +1,152 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+)
+
+// Client is a reusable handle on the VHI APIs for a single token. It keeps
+// the endpoints from the token's service catalog and one HTTP client, so
+// callers embedding this package can pool connections, cancel requests via
+// context and inject their own transport without touching viper state.
+//
+//	client := api.NewClient(tok)
+//	servers, err := client.Compute().ListServers(ctx, nil)
+type Client struct {
+	token     string
+	endpoints map[string]string
+	http      *httpclient.Client
+}
+
+// ClientOption configures a Client built by NewClient.
+type ClientOption func(*Client)
+
+// WithTransport sends all requests through rt, e.g. a test transport.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.http = httpclient.New(rt)
+	}
+}
+
+// WithHTTPClient shares an existing httpclient.Client between API clients.
+func WithHTTPClient(hc *httpclient.Client) ClientOption {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// NewClient returns a Client for the token value and endpoints in tok.
+func NewClient(tok Token, opts ...ClientOption) *Client {
+	c := &Client{
+		token:     tok.Value,
+		endpoints: tok.Endpoints,
+		http:      httpclient.Default,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// legacyClient wraps a bare token for the package-level functions, which
+// receive their endpoint URL explicitly instead of via the catalog.
+func legacyClient(token string) *Client {
+	return &Client{token: token, http: httpclient.Default}
+}
+
+// Token returns the auth token value the client sends.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// Endpoint returns the catalog URL for a service type such as "compute".
+func (c *Client) Endpoint(serviceType string) (string, error) {
+	url, ok := c.endpoints[serviceType]
+	if !ok || url == "" {
+		return "", fmt.Errorf("no '%s' endpoint found in token; re-auth or check your catalog", serviceType)
+	}
+	return url, nil
+}
+
+// service is the common part of the per-API service handles.
+type service struct {
+	c    *Client
+	name string
+	base string
+}
+
+func (c *Client) service(serviceType string) service {
+	return service{c: c, name: serviceType, base: c.endpoints[serviceType]}
+}
+
+// url joins the service base URL with a formatted path.
+func (s service) url(format string, args ...interface{}) (string, error) {
+	if s.base == "" {
+		return "", fmt.Errorf("no '%s' endpoint found in token; re-auth or check your catalog", s.name)
+	}
+	return s.base + fmt.Sprintf(format, args...), nil
+}
+
+// ComputeService groups Nova calls.
+type ComputeService struct{ service }
+
+// ImageService groups Glance calls.
+type ImageService struct{ service }
+
+// NetworkService groups Neutron calls.
+type NetworkService struct{ service }
+
+// VolumeService groups Cinder (volumev3) calls.
+type VolumeService struct{ service }
+
+// IdentityService groups Keystone calls.
+type IdentityService struct{ service }
+
+// Compute returns the Nova service handle.
+func (c *Client) Compute() *ComputeService {
+	return &ComputeService{c.service("compute")}
+}
+
+// Image returns the Glance service handle.
+func (c *Client) Image() *ImageService {
+	return &ImageService{c.service("image")}
+}
+
+// Network returns the Neutron service handle.
+func (c *Client) Network() *NetworkService {
+	return &NetworkService{c.service("network")}
+}
+
+// Volume returns the Cinder service handle.
+func (c *Client) Volume() *VolumeService {
+	return &VolumeService{c.service("volumev3")}
+}
+
+// Identity returns the Keystone service handle.
+func (c *Client) Identity() *IdentityService {
+	return &IdentityService{c.service("identity")}
+}
+
+// computeAt and friends build service handles for a fixed URL and token, so
+// the package-level functions share the service implementations.
+func computeAt(computeURL, token string) *ComputeService {
+	return &ComputeService{service{c: legacyClient(token), name: "compute", base: computeURL}}
+}
+
+func imageAt(imageURL, token string) *ImageService {
+	return &ImageService{service{c: legacyClient(token), name: "image", base: imageURL}}
+}
+
+func networkAt(networkURL, token string) *NetworkService {
+	return &NetworkService{service{c: legacyClient(token), name: "network", base: networkURL}}
+}
+
+func volumeAt(storageURL, token string) *VolumeService {
+	return &VolumeService{service{c: legacyClient(token), name: "volumev3", base: storageURL}}
+}
+
+func identityAt(identityURL, token string) *IdentityService {
+	return &IdentityService{service{c: legacyClient(token), name: "identity", base: identityURL}}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func jsonResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClientListServers(t *testing.T) {
+	var gotURL, gotToken string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotURL = r.URL.String()
+		gotToken = r.Header.Get("X-Auth-Token")
+		return jsonResponse(200, `{"servers":[{"id":"abc","name":"web-1"}]}`), nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"compute": "https://vhi.example/compute/v2.1"}}
+	client := NewClient(tok, WithTransport(rt))
+
+	resp, err := client.Compute().ListServers(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListServers: %v", err)
+	}
+	if gotURL != "https://vhi.example/compute/v2.1/servers" {
+		t.Errorf("unexpected URL %q", gotURL)
+	}
+	if gotToken != "tok123" {
+		t.Errorf("unexpected token %q", gotToken)
+	}
+	if len(resp.Servers) != 1 || resp.Servers[0].Name != "web-1" {
+		t.Errorf("unexpected servers %+v", resp.Servers)
+	}
+}
+
+func TestClientMissingEndpoint(t *testing.T) {
+	client := NewClient(Token{Value: "tok123"}, WithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request to %s", r.URL)
+		return nil, nil
+	})))
+
+	if _, err := client.Image().ListImages(context.Background(), nil); err == nil {
+		t.Fatal("expected error for missing image endpoint")
+	}
+}
+
+func TestWaitForStatusStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "/v2.1/") {
+			return jsonResponse(200, `{"version":{"version":"2.79","min_version":"2.1"}}`), nil
+		}
+		calls++
+		cancel()
+		return jsonResponse(200, `{"server":{"id":"abc","status":"BUILD","flavor":{"vcpus":1}}}`), nil
+	})
+	httpclient.Default = httpclient.New(rt)
+	t.Cleanup(func() { httpclient.Default = httpclient.New(nil) })
+
+	start := time.Now()
+	_, err := WaitForStatus(ctx, "https://vhi.example/compute/v2.1", "tok123", "abc", WaitOptions{}, "ACTIVE")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if calls != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("wait did not stop promptly: %d calls in %s", calls, time.Since(start))
+	}
+}
+
+func TestWaitForVMDeleted(t *testing.T) {
+	calls := 0
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "/v2.1/") {
+			return jsonResponse(200, `{"version":{"version":"2.79","min_version":"2.1"}}`), nil
+		}
+		calls++
+		if calls == 1 {
+			return jsonResponse(200, `{"server":{"id":"abc","status":"ACTIVE","OS-EXT-STS:task_state":"deleting","flavor":{"vcpus":1}}}`), nil
+		}
+		return jsonResponse(404, `{"itemNotFound":{"code":404,"message":"Instance abc could not be found."}}`), nil
+	})
+	httpclient.Default = httpclient.New(rt)
+	t.Cleanup(func() { httpclient.Default = httpclient.New(nil) })
+
+	err := WaitForVMDeleted(context.Background(), "https://vhi.example/compute/v2.1", "tok123", "abc", WaitOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("WaitForVMDeleted: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 status checks, got %d", calls)
+	}
+}
@@ -25,7 +25,7 @@ func ListDomains(identityUrl, token string) (DomainListResponse, error) {
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list domains failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list domains failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -2,10 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
-
-	"github.com/gookit/color"
 )
 
 // ErrorResponse represents the standard error response from the API
@@ -15,40 +16,85 @@ type ErrorResponse struct {
 	Title   string `json:"title"`
 }
 
-// FormatErrorResponse takes an error response body and returns a clean, formatted error message
-func FormatErrorResponse(responseBody string) string {
-	var genericMap map[string]interface{}
-	if err := json.Unmarshal([]byte(responseBody), &genericMap); err == nil {
-		errorKeys := []string{
-			"badRequest",
-			"NeutronError",
-			"itemNotFound",
-			"computeFault",
-			"unauthorizedError",
-			"notFound",
-			"forbidden",
-			"conflictingRequest",
-			"overLimit",
-			"serverCapacityUnavailable",
-			"serviceUnavailable",
-			"volumeBackendAPIException",
-			"HTTPBadRequest",
-			"internalServerError", // 500s
-			"invalidInput",
-			"resourceNotFound",
-			"quotaExceeded",
-			"imageUnacceptable",
-			"connectionRefused",
-			"volumeFault",
-			"deploymentErrors",
-			"resourceInUse",
+// Error is a failed API call. Fault is the OpenStack fault key from the
+// response body, e.g. "itemNotFound" or "overLimit", when there is one.
+// Use errors.As, or the Is* helpers, to inspect it through wrapping.
+type Error struct {
+	StatusCode int
+	Fault      string
+	RequestID  string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("[%d", e.StatusCode)
+	if e.Fault != "" {
+		s += " " + e.Fault
+	}
+	s += "]"
+	if e.Message != "" {
+		s += " " + e.Message
+	}
+	if e.RequestID != "" {
+		s += " (request " + e.RequestID + ")"
+	}
+	return s
+}
+
+// errorFaults are the fault keys OpenStack services wrap error messages in.
+var errorFaults = []string{
+	"badRequest",
+	"NeutronError",
+	"itemNotFound",
+	"computeFault",
+	"unauthorizedError",
+	"notFound",
+	"forbidden",
+	"conflictingRequest",
+	"overLimit",
+	"serverCapacityUnavailable",
+	"serviceUnavailable",
+	"volumeBackendAPIException",
+	"HTTPBadRequest",
+	"internalServerError", // 500s
+	"invalidInput",
+	"resourceNotFound",
+	"quotaExceeded",
+	"imageUnacceptable",
+	"connectionRefused",
+	"volumeFault",
+	"deploymentErrors",
+	"resourceInUse",
+}
+
+// newError builds an Error from a failed response.
+func newError(statusCode int, header http.Header, body string) *Error {
+	fault, msg := parseErrorBody(body)
+	e := &Error{StatusCode: statusCode, Fault: fault, Message: msg}
+	for _, h := range []string{"X-Openstack-Request-Id", "X-Compute-Request-Id", "X-Request-Id"} {
+		if id := header.Get(h); id != "" {
+			e.RequestID = id
+			break
 		}
+	}
+	return e
+}
 
-		for _, key := range errorKeys {
+// parseErrorBody extracts the fault key and message from an error body.
+// Neutron nests its fault type under "NeutronError"; that type is used as
+// the fault when present.
+func parseErrorBody(responseBody string) (fault, message string) {
+	var genericMap map[string]interface{}
+	if err := json.Unmarshal([]byte(responseBody), &genericMap); err == nil {
+		for _, key := range errorFaults {
 			if errorObj, ok := genericMap[key].(map[string]interface{}); ok {
+				fault = key
+				if t, ok := errorObj["type"].(string); ok && key == "NeutronError" && t != "" {
+					fault = t
+				}
 				for _, msgKey := range []string{"message", "Message"} {
 					if msg, exists := errorObj[msgKey].(string); exists {
-						return color.Style{color.FgRed}.Render(cleanErrorMessage(msg))
+						return fault, cleanErrorMessage(msg)
 					}
 				}
 			}
@@ -56,17 +102,23 @@ func FormatErrorResponse(responseBody string) string {
 
 		for _, msgKey := range []string{"message", "Message", "error", "error_message"} {
 			if msg, ok := genericMap[msgKey].(string); ok {
-				return color.Style{color.FgRed}.Render(cleanErrorMessage(msg))
+				return fault, cleanErrorMessage(msg)
 			}
 		}
 	}
 
 	var errResp ErrorResponse
 	if err := json.Unmarshal([]byte(responseBody), &errResp); err == nil && errResp.Message != "" {
-		return color.Style{color.FgRed}.Render(cleanErrorMessage(errResp.Message))
+		return fault, cleanErrorMessage(errResp.Message)
 	}
 
-	return color.Style{color.FgRed}.Render(cleanErrorMessage(responseBody))
+	return fault, cleanErrorMessage(responseBody)
+}
+
+// FormatErrorResponse takes an error response body and returns a clean, formatted error message
+func FormatErrorResponse(responseBody string) string {
+	_, msg := parseErrorBody(responseBody)
+	return msg
 }
 
 // cleanErrorMessage removes HTML tags and cleans up common formatting issues
@@ -84,3 +136,79 @@ func cleanErrorMessage(msg string) string {
 
 	return msg
 }
+
+// fail returns the error for a response a caller didn't expect, prefixed
+// with what was being attempted. A 2xx status the caller wasn't expecting
+// is still reported as an Error, carrying the body as its message.
+func (r ApiResponse) fail(what string) error {
+	e := r.Err
+	if e == nil {
+		e = &Error{StatusCode: r.ResponseCode, Message: cleanErrorMessage(r.Response)}
+	}
+	return fmt.Errorf("%s: %w", what, e)
+}
+
+// AsError returns the API error wrapped in err, if there is one.
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+func hasStatusOrFault(err error, statuses []int, faults ...string) bool {
+	e, ok := AsError(err)
+	if !ok {
+		return false
+	}
+	for _, s := range statuses {
+		if e.StatusCode == s {
+			return true
+		}
+	}
+	for _, f := range faults {
+		if strings.EqualFold(e.Fault, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an API "not found" error.
+func IsNotFound(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusNotFound}, "itemNotFound", "notFound", "resourceNotFound")
+}
+
+// IsUnauthorized reports whether err is an authentication failure, e.g. an
+// expired or revoked token.
+func IsUnauthorized(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusUnauthorized}, "unauthorizedError")
+}
+
+// IsForbidden reports whether the token lacks permission for the call.
+func IsForbidden(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusForbidden}, "forbidden")
+}
+
+// IsBadRequest reports whether the API rejected the request as invalid.
+func IsBadRequest(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusBadRequest}, "badRequest", "HTTPBadRequest", "invalidInput")
+}
+
+// IsConflict reports whether the call conflicts with the resource's current
+// state, e.g. deleting a volume that is in use.
+func IsConflict(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusConflict}, "conflictingRequest", "resourceInUse")
+}
+
+// IsOverLimit reports whether a quota or rate limit was hit.
+func IsOverLimit(err error) bool {
+	return hasStatusOrFault(err, []int{http.StatusRequestEntityTooLarge, http.StatusTooManyRequests}, "overLimit", "quotaExceeded", "OverQuota")
+}
+
+// IsServerError reports whether the service failed with a 5xx status.
+func IsServerError(err error) bool {
+	e, ok := AsError(err)
+	return ok && e.StatusCode >= 500
+}
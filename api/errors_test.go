@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCallReturnsTypedError(t *testing.T) {
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := jsonResponse(404, `{"itemNotFound": {"code": 404, "message": "Instance <b>abc</b> could not be found."}}`)
+		resp.Header.Set("X-Openstack-Request-Id", "req-123")
+		return resp, nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"compute": "https://vhi.example/compute/v2.1"}}
+	_, err := NewClient(tok, WithTransport(rt)).Compute().GetServer(context.Background(), "abc")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !IsNotFound(err) {
+		t.Errorf("IsNotFound(%v) = false", err)
+	}
+	if IsConflict(err) || IsServerError(err) {
+		t.Errorf("404 misclassified: %v", err)
+	}
+
+	apiErr, ok := AsError(err)
+	if !ok {
+		t.Fatalf("no *Error in %v", err)
+	}
+	if apiErr.Fault != "itemNotFound" || apiErr.RequestID != "req-123" || apiErr.Message != "Instance abc could not be found." {
+		t.Errorf("unexpected error fields %+v", apiErr)
+	}
+	if strings.Contains(err.Error(), "\x1b[") {
+		t.Errorf("error message contains ANSI codes: %q", err.Error())
+	}
+}
+
+func TestParseErrorBody(t *testing.T) {
+	tests := []struct {
+		body, fault, msg string
+	}{
+		{`{"overLimit": {"message": "Quota exceeded for cores"}}`, "overLimit", "Quota exceeded for cores"},
+		{`{"NeutronError": {"type": "PortNotFound", "message": "Port x could not be found."}}`, "PortNotFound", "Port x could not be found."},
+		{`{"error": "bad things"}`, "", "bad things"},
+		{`<html><body>502 Bad Gateway</body></html>`, "", "502 Bad Gateway"},
+	}
+	for _, tt := range tests {
+		fault, msg := parseErrorBody(tt.body)
+		if fault != tt.fault || msg != tt.msg {
+			t.Errorf("parseErrorBody(%q) = %q, %q; want %q, %q", tt.body, fault, msg, tt.fault, tt.msg)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -41,9 +42,17 @@ type FlavorListResponse struct {
 
 // ListFlavors fetches the list of flavors from the stored compute URL
 func ListFlavors(computeURL, token string, queryParams map[string]string) (FlavorListResponse, error) {
+	return computeAt(computeURL, token).ListFlavors(context.Background(), queryParams)
+}
+
+// ListFlavors fetches the list of flavors.
+func (s *ComputeService) ListFlavors(ctx context.Context, queryParams map[string]string) (FlavorListResponse, error) {
 	var result FlavorListResponse
 
-	url := fmt.Sprintf("%s/flavors", computeURL)
+	url, err := s.url("/flavors")
+	if err != nil {
+		return result, err
+	}
 
 	if len(queryParams) > 0 {
 		url += "?"
@@ -53,12 +62,12 @@ func ListFlavors(computeURL, token string, queryParams map[string]string) (Flavo
 		url = url[:len(url)-1]
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch flavors: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("flavors request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("flavors request failed")
 	}
 
 	if err := json.Unmarshal([]byte(apiResp.Response), &result); err != nil {
@@ -69,16 +78,23 @@ func ListFlavors(computeURL, token string, queryParams map[string]string) (Flavo
 
 // GetFlavorDetails fetches the details of a single flavor from the stored compute URL
 func GetFlavorDetails(computeURL, token, flavorID string) (FlavorDetailResp, error) {
+	return computeAt(computeURL, token).GetFlavor(context.Background(), flavorID)
+}
+
+// GetFlavor fetches the details of a single flavor.
+func (s *ComputeService) GetFlavor(ctx context.Context, flavorID string) (FlavorDetailResp, error) {
 	var result FlavorDetailResp
 
-	url := fmt.Sprintf("%s/flavors/%s", computeURL, flavorID)
-	apiResp, err := callGET(url, token)
+	url, err := s.url("/flavors/%s", flavorID)
+	if err != nil {
+		return result, err
+	}
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to GET flavor: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("flavor details request failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("flavor details request failed")
 	}
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
 	if err != nil {
@@ -124,15 +140,14 @@ func GetFlavorIDByName(computeURL, token, flavorName string) (string, error) {
 		return flavorName, nil
 	}
 
-	flavors, err := ListFlavors(computeURL, token, nil)
-
+	flavors, err := computeAt(computeURL, token).FlavorPager(nil, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	var foundFlavors []Flavor
 
-	for _, flavor := range flavors.Flavors {
+	for _, flavor := range flavors {
 		if flavor.Name == flavorName {
 			foundFlavors = append(foundFlavors, flavor)
 		}
@@ -23,13 +23,13 @@ func ListHosts(computeURL, token string) (HostListResponse, error) {
 	var result HostListResponse
 
 	url := fmt.Sprintf("%s/os-hypervisors/detail", computeURL)
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch hosts: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("hosts request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("hosts request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -52,13 +52,13 @@ func GetHostDetails(computeURL, token, hostName string) (Host, error) {
 	var result Host
 
 	url := fmt.Sprintf("%s/os-hypervisors/%s", computeURL, hostName)
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch host details: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("host details request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("host details request failed")
 	}
 
 	var wrapper struct {
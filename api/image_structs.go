@@ -95,3 +95,17 @@ type ImageDetails struct {
 	OsDistro         string   `json:"os_distro"`
 	ImageValidated   string   `json:"image_validated"`
 }
+
+// VMSnapshot is a Glance image made by snapshotting a VM. Nova sets
+// instance_uuid on it; for a volume-backed VM the image holds no data and
+// its block_device_mapping lists a Cinder snapshot of each attached volume.
+type VMSnapshot struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Status             string `json:"status"`
+	Size               int64  `json:"size"`
+	MinDisk            int    `json:"min_disk"`
+	CreatedAt          string `json:"created_at"`
+	InstanceUUID       string `json:"instance_uuid"`
+	BlockDeviceMapping string `json:"block_device_mapping,omitempty"` // JSON list
+}
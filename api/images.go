@@ -2,10 +2,10 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
 	"os"
 	"time"
 
@@ -15,23 +15,30 @@ import (
 
 // GetImageDetails fetches detailed information about a specific image
 func GetImageDetails(imageURL, token, imageID string) (ImageDetails, error) {
+	return imageAt(imageURL, token).GetImage(context.Background(), imageID)
+}
+
+// GetImage fetches detailed information about a specific image.
+func (s *ImageService) GetImage(ctx context.Context, imageID string) (ImageDetails, error) {
 	var result ImageDetails
 
-	url := fmt.Sprintf("%s/v2/images/%s", imageURL, imageID)
+	url, err := s.url("/v2/images/%s", imageID)
+	if err != nil {
+		return result, err
+	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to get image details: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("image details request failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("image details request failed")
 	}
 
 	// For debugging
 	if viper.GetBool("debug") {
-		fmt.Printf("\nUnmarshaling response into struct: %s\n", apiResp.Response)
+		fmt.Fprintf(os.Stderr, "\nUnmarshaling response into struct: %s\n", apiResp.Response)
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -41,7 +48,7 @@ func GetImageDetails(imageURL, token, imageID string) (ImageDetails, error) {
 
 	// For debugging
 	if viper.GetBool("debug") {
-		fmt.Printf("\nParsed struct: %+v\n", result)
+		fmt.Fprintf(os.Stderr, "\nParsed struct: %+v\n", result)
 	}
 
 	return result, nil
@@ -49,85 +56,49 @@ func GetImageDetails(imageURL, token, imageID string) (ImageDetails, error) {
 
 // ListImages fetches the list of images with optional filters and sorting.
 func ListImages(imageURL, token string, queryParams map[string]string) (ImageListResponse, error) {
-	var allImages []Image
+	return imageAt(imageURL, token).ListImages(context.Background(), queryParams)
+}
+
+// ListImages fetches the list of images with optional filters and sorting,
+// following Glance's next links until every page has been read.
+func (s *ImageService) ListImages(ctx context.Context, queryParams map[string]string) (ImageListResponse, error) {
 	var result ImageListResponse
 
 	// Set default limit if not provided
-	currentParams := make(map[string]string)
-	for k, v := range queryParams {
-		currentParams[k] = v
+	pageSize := 0
+	if _, hasLimit := queryParams["limit"]; !hasLimit {
+		pageSize = DefaultPageSize
 	}
-	if _, hasLimit := currentParams["limit"]; !hasLimit {
-		currentParams["limit"] = "100"
-	}
-
-	for {
-		baseURL, err := url.Parse(fmt.Sprintf("%s/v2/images", imageURL))
-		if err != nil {
-			return result, fmt.Errorf("failed to parse URL: %v", err)
-		}
-
-		query := baseURL.Query()
-		for key, value := range currentParams {
-			query.Add(key, value)
-		}
-		baseURL.RawQuery = query.Encode()
-
-		apiResp, err := callGET(baseURL.String(), token)
-		if err != nil {
-			return result, fmt.Errorf("failed to fetch images: %v", err)
-		}
 
-		if apiResp.ResponseCode != 200 {
-			return result, fmt.Errorf("image list request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
-		}
-
-		var pageResult ImageListResponse
-		err = json.Unmarshal([]byte(apiResp.Response), &pageResult)
-		if err != nil {
-			return result, fmt.Errorf("failed to parse image list response: %v", err)
-		}
-
-		// Add images from this page
-		allImages = append(allImages, pageResult.Images...)
-
-		// Check if there's a next page
-		if pageResult.Next == "" {
-			break
-		}
-
-		// Parse the next URL to get the marker parameter
-		nextURL, err := url.Parse(pageResult.Next)
-		if err != nil {
-			return result, fmt.Errorf("failed to parse next URL: %v", err)
-		}
-
-		// Extract marker from next URL
-		marker := nextURL.Query().Get("marker")
-		if marker == "" {
-			break
-		}
-
-		// Update parameters for next page
-		currentParams["marker"] = marker
+	images, err := s.ImagePager(queryParams, pageSize).All(ctx)
+	if err != nil {
+		return result, err
 	}
 
 	// Build final response with all images
-	result.Images = allImages
+	result.Images = images
 	result.Schema = "v2/schemas/images"
 	return result, nil
 }
 
 // DeleteImage deletes an image by ID.
 func DeleteImage(imageURL, token, imageID string) error {
-	url := fmt.Sprintf("%s/v2/images/%s", imageURL, imageID)
+	return imageAt(imageURL, token).DeleteImage(context.Background(), imageID)
+}
 
-	apiResp, err := callDELETE(url, token)
+// DeleteImage deletes an image by ID.
+func (s *ImageService) DeleteImage(ctx context.Context, imageID string) error {
+	url, err := s.url("/v2/images/%s", imageID)
+	if err != nil {
+		return err
+	}
+
+	apiResp, err := s.call(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete image: %v", err)
 	}
 	if apiResp.ResponseCode != 204 {
-		return fmt.Errorf("delete image request failed [%d]", apiResp.ResponseCode)
+		return apiResp.fail("delete image request failed")
 	}
 
 	return nil
@@ -144,7 +115,7 @@ func createImage(imageURL, token string, req CreateImageRequest) (string, error)
 	}
 
 	if apiResp.ResponseCode != 201 {
-		return "", fmt.Errorf("create failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return "", apiResp.fail("create failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -160,10 +131,10 @@ func uploadImageData(imageURL, token, imageID string, data io.Reader) error {
 	url := fmt.Sprintf("%s/v2/images/%s/file", imageURL, imageID)
 
 	if viper.GetBool("debug") {
-		fmt.Printf("Attempting upload to URL: %s\n", url)
+		fmt.Fprintf(os.Stderr, "Attempting upload to URL: %s\n", url)
 	}
 
-	resp, err := httpclient.UploadBigFile(url, token, data)
+	resp, err := httpclient.UploadBigFile(context.Background(), url, token, data)
 	if err != nil {
 		return fmt.Errorf("upload failed: %v", err)
 	}
@@ -171,18 +142,19 @@ func uploadImageData(imageURL, token, imageID string, data io.Reader) error {
 
 	if resp.StatusCode != 204 && resp.StatusCode != 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("upload failed: %w", newError(resp.StatusCode, resp.Header, string(bodyBytes)))
 	}
 
 	return nil
 }
 
-// CreateAndUploadImage creates an image and uploads the image data
-func CreateAndUploadImage(imageURL, token string, req CreateImageRequest, data io.Reader) (string, error) {
+// CreateAndUploadImage creates an image and uploads the image data. If the
+// upload fails or ctx is cancelled, the half-made image is deleted.
+func CreateAndUploadImage(ctx context.Context, imageURL, token string, req CreateImageRequest, data io.Reader) (string, error) {
 	debug := viper.GetBool("debug")
 
 	if debug {
-		fmt.Printf("Creating image entry in Glance...\n")
+		fmt.Fprintf(os.Stderr, "Creating image entry in Glance...\n")
 	}
 
 	if req.DiskFmt == "" {
@@ -198,29 +170,34 @@ func CreateAndUploadImage(imageURL, token string, req CreateImageRequest, data i
 	}
 
 	if debug {
-		fmt.Printf("Image entry created with ID: %s\n", imageID)
-		fmt.Printf("Waiting for image to be ready for upload...\n")
+		fmt.Fprintf(os.Stderr, "Image entry created with ID: %s\n", imageID)
+		fmt.Fprintf(os.Stderr, "Waiting for image to be ready for upload...\n")
 	}
 
 	// Wait for image to be in ready state
-	if err := waitForImageReady(imageURL, token, imageID, debug); err != nil {
-		return imageID, fmt.Errorf("image not ready: %v", err)
+	opts := WaitOptions{Interval: 3 * time.Second}
+	if debug {
+		opts.OnProgress = func(status string) { fmt.Fprintf(os.Stderr, "Image status: %s\n", status) }
+	}
+	if _, err := WaitForImageStatus(ctx, imageURL, token, imageID, "queued", opts); err != nil {
+		_ = DeleteImage(imageURL, token, imageID)
+		return imageID, fmt.Errorf("image not ready: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/v2/images/%s/file", imageURL, imageID)
 
-	resp, err := httpclient.UploadBigFile(url, token, data)
+	resp, err := httpclient.UploadBigFile(ctx, url, token, data)
 	if err != nil {
 		if debug {
-			fmt.Printf("Upload failed, cleaning up image entry...\n")
+			fmt.Fprintf(os.Stderr, "Upload failed, cleaning up image entry...\n")
 		}
 		_ = DeleteImage(imageURL, token, imageID)
-		return imageID, fmt.Errorf("failed to upload image data: %v", err)
+		return imageID, fmt.Errorf("failed to upload image data: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if debug {
-		fmt.Printf("Upload completed successfully\n")
+		fmt.Fprintf(os.Stderr, "Upload completed successfully\n")
 	}
 
 	return imageID, nil
@@ -261,13 +238,13 @@ func GetImageIDByName(imageURL, token, imageName string) (string, error) {
 		return imageName, nil
 	}
 
-	images, err := ListImages(imageURL, token, nil)
+	images, err := imageAt(imageURL, token).ImagePager(nil, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	foundImages := []Image{}
-	for _, image := range images.Images {
+	for _, image := range images {
 		if image.Name == imageName {
 			foundImages = append(foundImages, image)
 		}
@@ -285,35 +262,37 @@ func GetImageIDByName(imageURL, token, imageName string) (string, error) {
 
 // GetImageNameByID fetches the name of an image by its ID.
 func GetImageNameByID(imageURL, token, imageID string) (string, error) {
-	images, err := ListImages(imageURL, token, nil)
+	image, err := GetImageByID(imageURL, token, imageID)
 	if err != nil {
 		return "", err
 	}
-	imageName := ""
-	for _, image := range images.Images {
-		if image.ID == imageID {
-			imageName = image.Name
-			break
-		}
-	}
-	if imageName == "" {
-		return "", fmt.Errorf("no image found for ID %s", imageID)
-	}
-	return imageName, nil
+	return image.Name, nil
 }
 
-// GetImageByID fetches the details of an image by its ID.
+// GetImageByID fetches an image by its ID.
 func GetImageByID(imageURL, token, imageID string) (Image, error) {
-	images, err := ListImages(imageURL, token, nil)
+	return getImageByID(context.Background(), imageURL, token, imageID)
+}
+
+func getImageByID(ctx context.Context, imageURL, token, imageID string) (Image, error) {
+	var image Image
+
+	url := fmt.Sprintf("%s/v2/images/%s", imageURL, imageID)
+	apiResp, err := legacyClient(token).call(ctx, "GET", url, nil)
 	if err != nil {
-		return Image{}, err
+		return image, fmt.Errorf("failed to fetch image: %v", err)
 	}
-	for _, image := range images.Images {
-		if image.ID == imageID {
-			return image, nil
-		}
+	if apiResp.ResponseCode == 404 {
+		return image, apiResp.fail(fmt.Sprintf("no image found for ID %s", imageID))
 	}
-	return Image{}, fmt.Errorf("no image found for ID %s", imageID)
+	if apiResp.ResponseCode != 200 {
+		return image, apiResp.fail("image request failed")
+	}
+
+	if err := json.Unmarshal([]byte(apiResp.Response), &image); err != nil {
+		return image, fmt.Errorf("failed to parse image response: %v", err)
+	}
+	return image, nil
 }
 
 // GetImageSize fetches the size of an image by its ID.
@@ -325,35 +304,23 @@ func GetImageSize(imageURL, token, imageID string) (int64, error) {
 	return image.Size, nil
 }
 
-// waitForImageReady polls the image API until the image is in a ready state
-func waitForImageReady(imageURL, token, imageID string, debug bool) error {
-	deadline := time.Now().Add(15 * time.Second)
-
-	for time.Now().Before(deadline) {
-		image, err := GetImageByID(imageURL, token, imageID)
-		if err != nil {
-			if debug {
-				fmt.Printf("Error checking image status: %v\n", err)
-			}
-			return fmt.Errorf("failed to check image status: %v", err)
-		}
-
-		if debug {
-			fmt.Printf("Image status: %s\n", image.Status)
-		}
-
-		switch image.Status {
-		case "queued":
-			// Ready for upload
-			return nil
-		case "error":
-			return fmt.Errorf("image entered error state")
-		}
-
-		time.Sleep(3 * time.Second)
-	}
-
-	return fmt.Errorf("timeout waiting for image to be ready")
+// WaitForImageStatus waits until an image reaches the target status. An
+// image that is not visible yet is polled again rather than reported
+// missing, since volume uploads register it asynchronously.
+func WaitForImageStatus(ctx context.Context, imageURL, token, imageID, targetStatus string, opts WaitOptions) (Image, error) {
+	return Poller[Image]{
+		What: "image " + imageID,
+		Get: func(ctx context.Context) (Image, string, error) {
+			image, err := getImageByID(ctx, imageURL, token, imageID)
+			return image, image.Status, err
+		},
+		Target:     []string{targetStatus},
+		Failure:    []string{"killed", "deleted", "error"},
+		Interval:   opts.Interval,
+		Timeout:    opts.Timeout,
+		Retry:      IsNotFound,
+		OnProgress: opts.OnProgress,
+	}.Wait(ctx)
 }
 
 // UpdateImageVisibility updates the visibility of an image
@@ -370,7 +337,7 @@ func UpdateImageVisibility(imageURL, token, imageID, visibility string) error {
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("visibility update failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("visibility update failed")
 	}
 
 	return nil
@@ -388,7 +355,7 @@ func ListImageMembers(imageURL, token, imageID string) (ImageMemberList, error)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list members request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list members request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -412,7 +379,7 @@ func AddImageMember(imageURL, token, imageID, projectID string) error {
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("add member request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("add member request failed")
 	}
 
 	return nil
@@ -428,7 +395,7 @@ func RemoveImageMember(imageURL, token, imageID, memberID string) error {
 	}
 
 	if apiResp.ResponseCode != 204 {
-		return fmt.Errorf("remove member request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("remove member request failed")
 	}
 
 	return nil
@@ -448,25 +415,26 @@ func UpdateImageMemberStatus(imageURL, token, imageID, memberID, status string)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("update member status failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("update member status failed")
 	}
 
 	return nil
 }
 
-// DownloadImage downloads an image to local storage
-func DownloadImage(imageURL, token, imageID, outputPath string) error {
+// DownloadImage downloads an image to local storage. Cancelling ctx stops
+// the transfer and removes the partial file.
+func DownloadImage(ctx context.Context, imageURL, token, imageID, outputPath string) error {
 	url := fmt.Sprintf("%s/v2/images/%s/file", imageURL, imageID)
 
-	resp, err := httpclient.SendRequestWithToken("GET", url, token, nil)
+	resp, err := httpclient.Default.Do(ctx, "GET", url, token, nil)
 	if err != nil {
-		return fmt.Errorf("failed to download image: %v", err)
+		return fmt.Errorf("failed to download image: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("download failed [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("download failed: %w", newError(resp.StatusCode, resp.Header, string(bodyBytes)))
 	}
 
 	file, err := os.Create(outputPath)
@@ -477,6 +445,11 @@ func DownloadImage(imageURL, token, imageID, outputPath string) error {
 
 	_, err = io.Copy(file, resp.Body)
 	if err != nil {
+		file.Close()
+		os.Remove(outputPath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("failed to write image data: %v", err)
 	}
 
@@ -504,7 +477,7 @@ func UpdateImageProperties(imageURL, token, imageID string, properties map[strin
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("update properties failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("update properties failed")
 	}
 
 	return nil
@@ -535,7 +508,7 @@ func SetImageI440fx(imageURL, token, imageID string) error {
 
 	if resp.StatusCode != 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("set i440fx failed [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("set i440fx failed: %w", newError(resp.StatusCode, resp.Header, string(bodyBytes)))
 	}
 
 	return nil
@@ -571,7 +544,7 @@ func SetImageQ35(imageURL, token, imageID string) error {
 
 	if resp.StatusCode != 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("set q35 failed [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("set q35 failed: %w", newError(resp.StatusCode, resp.Header, string(bodyBytes)))
 	}
 
 	return nil
@@ -25,13 +25,13 @@ func GetLimits(computeURL, token string, includeReserved bool, tenantID string)
 		url += fmt.Sprintf("tenant_id=%s", tenantID)
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch limits: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("limits request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("limits request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
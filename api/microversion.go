@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+)
+
+// novaVersionHeader carries the Nova microversion. Only compute requests
+// send it; the other services ignore or reject it.
+const novaVersionHeader = "X-OpenStack-Nova-API-Version"
+
+// maxMicroversion is the newest Nova microversion whose request and response
+// formats this package handles. Newer clouds are asked for this one. 2.75
+// changes flavor swap to an integer and rejects unknown server list query
+// parameters, so raising it needs an audit of the structs and queries.
+var maxMicroversion = Microversion{2, 72}
+
+// Minimum microversions of features that need one.
+var (
+	serverTagsVersion      = Microversion{2, 26}
+	createImageIDVersion   = Microversion{2, 45}
+	rebuildUserDataVersion = Microversion{2, 57}
+	bdmVolumeTypeVersion   = Microversion{2, 67}
+
+	// Newer than maxMicroversion; sent only on the requests that need them
+	lockedReasonVersion      = Microversion{2, 73}
+	reimageBootVolumeVersion = Microversion{2, 93}
+)
+
+// serverFilters lists the server list filters that need a microversion.
+// Older clouds ignore unknown filters, which would silently list everything.
+var serverFilters = []struct {
+	param string
+	min   Microversion
+}{
+	{"tags", serverTagsVersion},
+	{"tags-any", serverTagsVersion},
+	{"not-tags", serverTagsVersion},
+	{"not-tags-any", serverTagsVersion},
+}
+
+// Microversion is a Nova API microversion such as 2.72. The zero value means
+// the cloud has no microversions and gets the 2.1 baseline.
+type Microversion struct {
+	Major, Minor int
+}
+
+// ParseMicroversion parses "2.72". An empty string gives the zero value.
+func ParseMicroversion(s string) (Microversion, error) {
+	if s == "" {
+		return Microversion{}, nil
+	}
+	major, minor, ok := strings.Cut(s, ".")
+	if !ok {
+		return Microversion{}, fmt.Errorf("invalid microversion %q", s)
+	}
+	ma, err1 := strconv.Atoi(major)
+	mi, err2 := strconv.Atoi(minor)
+	if err1 != nil || err2 != nil {
+		return Microversion{}, fmt.Errorf("invalid microversion %q", s)
+	}
+	return Microversion{ma, mi}, nil
+}
+
+func (v Microversion) String() string {
+	if v.IsZero() {
+		return "2.1 (no microversions)"
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// IsZero reports whether v is unset.
+func (v Microversion) IsZero() bool {
+	return v == Microversion{}
+}
+
+// AtLeast reports whether v is o or newer.
+func (v Microversion) AtLeast(o Microversion) bool {
+	if v.Major != o.Major {
+		return v.Major > o.Major
+	}
+	return v.Minor >= o.Minor
+}
+
+// MicroversionError is returned when an operation needs a newer Nova
+// microversion than the cloud offers.
+type MicroversionError struct {
+	Feature   string
+	Required  Microversion
+	Supported Microversion
+	Err       error // why negotiation failed, if it did
+}
+
+func (e *MicroversionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s requires Nova API microversion %s, but the compute version could not be determined: %v", e.Feature, e.Required, e.Err)
+	}
+	return fmt.Sprintf("%s requires Nova API microversion %s; this cloud supports up to %s", e.Feature, e.Required, e.Supported)
+}
+
+func (e *MicroversionError) Unwrap() error { return e.Err }
+
+// negotiated microversions, by compute version root. A successful
+// negotiation happens once per process and endpoint; a failed one is tried
+// again on the next request.
+var microversions struct {
+	sync.Mutex
+	byRoot map[string]negotiation
+	warned map[string]bool // roots a fallback warning was printed for
+}
+
+type negotiation struct {
+	version Microversion // what to use: the cloud's latest, capped at maxMicroversion
+	latest  Microversion // the newest the cloud supports
+	err     error
+}
+
+// versionRoot trims a compute endpoint to the URL of its version document,
+// e.g. https://vhi:8774/v2.1/<project> to https://vhi:8774/v2.1.
+func versionRoot(computeURL string) string {
+	u := strings.TrimRight(computeURL, "/")
+	if i := strings.Index(u, "/v2.1"); i >= 0 {
+		return u[:i+len("/v2.1")]
+	}
+	return u
+}
+
+// microversion returns the microversion to use with the compute API,
+// negotiating it on first use. If the version document can't be read the
+// zero value is returned along with the reason.
+func (s service) microversion(ctx context.Context) (Microversion, error) {
+	n := s.negotiate(ctx)
+	return n.version, n.err
+}
+
+// negotiate returns the negotiation for the service's compute endpoint,
+// reading the version document on first use.
+func (s service) negotiate(ctx context.Context) negotiation {
+	if s.base == "" {
+		return negotiation{err: fmt.Errorf("no '%s' endpoint found in token; re-auth or check your catalog", s.name)}
+	}
+	root := versionRoot(s.base)
+
+	microversions.Lock()
+	n, ok := microversions.byRoot[root]
+	microversions.Unlock()
+	if ok {
+		return n
+	}
+
+	n = negotiateMicroversion(ctx, s.c, root)
+	if n.err != nil {
+		return n // cancelled or failed; try again next time
+	}
+
+	microversions.Lock()
+	if microversions.byRoot == nil {
+		microversions.byRoot = make(map[string]negotiation)
+	}
+	microversions.byRoot[root] = n
+	microversions.Unlock()
+	return n
+}
+
+// negotiateMicroversion reads the compute version document at root and
+// picks the newest microversion both sides support.
+func negotiateMicroversion(ctx context.Context, c *Client, root string) negotiation {
+	apiResp, err := c.call(ctx, "GET", root+"/", nil)
+	if err != nil {
+		return negotiation{err: fmt.Errorf("failed to get compute version: %v", err)}
+	}
+	if apiResp.ResponseCode != 200 {
+		return negotiation{err: apiResp.fail("compute version request failed")}
+	}
+
+	var doc struct {
+		Version struct {
+			Version    string `json:"version"`
+			MinVersion string `json:"min_version"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(apiResp.Response), &doc); err != nil {
+		return negotiation{err: fmt.Errorf("failed to parse compute version response: %v", err)}
+	}
+
+	max, err := ParseMicroversion(doc.Version.Version)
+	if err != nil {
+		return negotiation{err: err}
+	}
+	min, err := ParseMicroversion(doc.Version.MinVersion)
+	if err != nil {
+		return negotiation{err: err}
+	}
+
+	switch {
+	case max.IsZero():
+		return negotiation{}
+	case !min.IsZero() && !maxMicroversion.AtLeast(min):
+		return negotiation{latest: max, err: fmt.Errorf("compute API requires microversion %s or newer; vhicmd supports up to %s", min, maxMicroversion)}
+	case max.AtLeast(maxMicroversion):
+		return negotiation{version: maxMicroversion, latest: max}
+	}
+	return negotiation{version: max, latest: max}
+}
+
+// microversionKey carries a microversion set by withMicroversion.
+type microversionKey struct{}
+
+// call sends a request through the service's client. Compute requests carry
+// the negotiated microversion, or the one set by withMicroversion.
+func (s service) call(ctx context.Context, method, url string, body interface{}) (ApiResponse, error) {
+	if s.name == "compute" {
+		v, ok := ctx.Value(microversionKey{}).(Microversion)
+		if !ok {
+			var err error
+			if v, err = s.microversion(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ApiResponse{}, ctx.Err()
+				}
+				v = maxMicroversion
+				s.warnFallback(err)
+			}
+		}
+		if !v.IsZero() {
+			ctx = httpclient.WithHeader(ctx, novaVersionHeader, v.String())
+		}
+	}
+	return s.c.call(ctx, method, url, body)
+}
+
+// warnFallback reports on stderr, once per endpoint, that requests are
+// sent with maxMicroversion because negotiation failed.
+func (s service) warnFallback(err error) {
+	root := versionRoot(s.base)
+	microversions.Lock()
+	defer microversions.Unlock()
+	if microversions.warned[root] {
+		return
+	}
+	if microversions.warned == nil {
+		microversions.warned = make(map[string]bool)
+	}
+	microversions.warned[root] = true
+	fmt.Fprintf(os.Stderr, "Warning: %v; using Nova API microversion %s\n", err, maxMicroversion)
+}
+
+// require fails with a *MicroversionError unless the compute API supports
+// at least min.
+func (s *ComputeService) require(ctx context.Context, feature string, min Microversion) error {
+	v, err := s.microversion(ctx)
+	if err == nil && v.AtLeast(min) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return &MicroversionError{Feature: feature, Required: min, Supported: v, Err: err}
+}
+
+// withMicroversion returns a context whose compute requests ask for min,
+// failing with a *MicroversionError if the cloud doesn't offer it. It is for
+// the few requests that need a newer microversion than maxMicroversion: only
+// their responses are read in the newer format, so keep such a context to
+// those requests.
+func (s *ComputeService) withMicroversion(ctx context.Context, feature string, min Microversion) (context.Context, error) {
+	n := s.negotiate(ctx)
+	if n.err == nil && n.latest.AtLeast(min) {
+		return context.WithValue(ctx, microversionKey{}, min), nil
+	}
+	if ctx.Err() != nil {
+		return ctx, ctx.Err()
+	}
+	return ctx, &MicroversionError{Feature: feature, Required: min, Supported: n.latest, Err: n.err}
+}
+
+// checkServerQuery fails if queryParams use a server list filter the
+// compute API is too old for.
+func (s *ComputeService) checkServerQuery(ctx context.Context, queryParams map[string]string) error {
+	for _, f := range serverFilters {
+		if _, ok := queryParams[f.param]; ok {
+			if err := s.require(ctx, "the "+f.param+" filter", f.min); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// callNova sends a request to the compute API at computeURL, as the
+// package-level functions do, with the negotiated microversion.
+func callNova(computeURL, token, method, url string, body interface{}) (ApiResponse, error) {
+	return computeAt(computeURL, token).call(context.Background(), method, url, body)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+)
+
+func TestMicroversionNegotiation(t *testing.T) {
+	sent := map[string]string{}
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case r.URL.Path == "/compute/v2.1/":
+			return jsonResponse(200, `{"version":{"id":"v2.1","version":"2.60","min_version":"2.1"}}`), nil
+		case strings.HasPrefix(r.URL.Path, "/compute/"):
+			sent["compute"] = r.Header.Get(novaVersionHeader)
+			return jsonResponse(200, `{"server":{"id":"abc","status":"ACTIVE"}}`), nil
+		default:
+			sent["image"] = r.Header.Get(novaVersionHeader)
+			return jsonResponse(200, `{"images":[]}`), nil
+		}
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{
+		"compute": "https://old-vhi.example/compute/v2.1/proj1",
+		"image":   "https://old-vhi.example/image",
+	}}
+	client := NewClient(tok, WithTransport(rt))
+	ctx := context.Background()
+
+	if _, err := client.Compute().GetServer(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Image().ListImages(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sent["compute"] != "2.60" {
+		t.Errorf("compute request sent microversion %q, want 2.60", sent["compute"])
+	}
+	if sent["image"] != "" {
+		t.Errorf("image request sent microversion %q", sent["image"])
+	}
+
+	var req CreateVMRequest
+	req.Server.BlockDeviceMappingV2 = []map[string]interface{}{{"volume_type": "fast"}}
+	_, err := client.Compute().CreateServer(ctx, req)
+	var me *MicroversionError
+	if !errors.As(err, &me) || me.Required != bdmVolumeTypeVersion || me.Supported != (Microversion{2, 60}) {
+		t.Errorf("expected a microversion error, got %v", err)
+	}
+}
+
+func TestParseMicroversion(t *testing.T) {
+	v, err := ParseMicroversion("2.72")
+	if err != nil || v != (Microversion{2, 72}) {
+		t.Errorf("ParseMicroversion(2.72) = %v, %v", v, err)
+	}
+	if !v.AtLeast(Microversion{2, 9}) || v.AtLeast(Microversion{2, 73}) {
+		t.Errorf("AtLeast compares %v wrongly", v)
+	}
+	if _, err := ParseMicroversion("v2"); err == nil {
+		t.Error("expected an error for v2")
+	}
+}
+
+func TestServerTagFilterNeedsMicroversion(t *testing.T) {
+	listed := false
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/compute/v2.1/" {
+			return jsonResponse(200, `{"version":{"version":"2.20","min_version":"2.1"}}`), nil
+		}
+		listed = true
+		return jsonResponse(200, `{"servers":[]}`), nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"compute": "https://tagless-vhi.example/compute/v2.1"}}
+	pager := NewClient(tok, WithTransport(rt)).Compute().ServerDetailPager(map[string]string{"tags": "team-a"}, 0)
+	if _, err := pager.NextPage(context.Background()); err == nil {
+		t.Fatal("expected an error for a tags filter on microversion 2.20")
+	}
+	if listed {
+		t.Error("servers were listed without the tags filter being supported")
+	}
+}
+
+func TestRebuildVolumeBackedVM(t *testing.T) {
+	sent := map[string]string{}
+	rt := func(latest string) roundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			switch {
+			case r.URL.Path == "/compute/v2.1/":
+				return jsonResponse(200, `{"version":{"version":"`+latest+`","min_version":"2.1"}}`), nil
+			case r.Method == "POST":
+				sent["rebuild"] = r.Header.Get(novaVersionHeader)
+				return jsonResponse(202, `{"server":{"id":"abc"}}`), nil
+			default:
+				sent["get"] = r.Header.Get(novaVersionHeader)
+				return jsonResponse(200, `{"server":{"id":"abc","status":"ACTIVE","image":"","flavor":{"vcpus":1}}}`), nil
+			}
+		}
+	}
+	t.Cleanup(func() { httpclient.Default = httpclient.New(nil) })
+
+	httpclient.Default = httpclient.New(rt("2.95"))
+	err := RebuildVM(context.Background(), "https://new-vhi.example/compute/v2.1", "tok123", "abc", RebuildVMRequest{ImageRef: "img"}, WaitOptions{})
+	if err != nil {
+		t.Fatalf("RebuildVM: %v", err)
+	}
+	if sent["rebuild"] != "2.93" || sent["get"] != maxMicroversion.String() {
+		t.Errorf("rebuild sent %q and status checks %q, want 2.93 and %s", sent["rebuild"], sent["get"], maxMicroversion)
+	}
+
+	delete(sent, "rebuild")
+	httpclient.Default = httpclient.New(rt("2.79"))
+	err = RebuildVM(context.Background(), "https://older-vhi.example/compute/v2.1", "tok123", "abc", RebuildVMRequest{ImageRef: "img"}, WaitOptions{})
+	var me *MicroversionError
+	if !errors.As(err, &me) || me.Required != reimageBootVolumeVersion {
+		t.Errorf("expected a microversion error, got %v", err)
+	}
+	if _, ok := sent["rebuild"]; ok {
+		t.Error("rebuild was sent to a cloud without microversion 2.93")
+	}
+}
+
+func TestLockReasonMicroversion(t *testing.T) {
+	var sent []string
+	rt := func(latest string) roundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/compute/v2.1/" {
+				return jsonResponse(200, `{"version":{"version":"`+latest+`","min_version":"2.1"}}`), nil
+			}
+			sent = append(sent, r.Header.Get(novaVersionHeader))
+			return jsonResponse(202, ``), nil
+		}
+	}
+	t.Cleanup(func() { httpclient.Default = httpclient.New(nil) })
+
+	httpclient.Default = httpclient.New(rt("2.79"))
+	if err := LockVM("https://lock-vhi.example/compute/v2.1", "tok123", "abc", "maintenance"); err != nil {
+		t.Fatalf("LockVM with a reason: %v", err)
+	}
+	if err := LockVM("https://lock-vhi.example/compute/v2.1", "tok123", "abc", ""); err != nil {
+		t.Fatalf("LockVM: %v", err)
+	}
+	if len(sent) != 2 || sent[0] != "2.73" || sent[1] != maxMicroversion.String() {
+		t.Errorf("lock requests sent %q, want 2.73 then %s", sent, maxMicroversion)
+	}
+
+	sent = nil
+	httpclient.Default = httpclient.New(rt("2.72"))
+	err := LockVM("https://old-lock-vhi.example/compute/v2.1", "tok123", "abc", "maintenance")
+	var me *MicroversionError
+	if !errors.As(err, &me) || me.Required != lockedReasonVersion {
+		t.Errorf("expected a microversion error, got %v", err)
+	}
+	if len(sent) != 0 {
+		t.Error("lock with a reason was sent to a cloud without microversion 2.73")
+	}
+}
+
+func TestFailedNegotiationIsRetried(t *testing.T) {
+	docStatus := 404
+	var sent []string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/compute/v2.1/" {
+			if docStatus != 200 {
+				return jsonResponse(docStatus, `{"itemNotFound":{"code":404,"message":"Not found"}}`), nil
+			}
+			return jsonResponse(200, `{"version":{"version":"2.60","min_version":"2.1"}}`), nil
+		}
+		sent = append(sent, r.Header.Get(novaVersionHeader))
+		return jsonResponse(200, `{"server":{"id":"abc","status":"ACTIVE"}}`), nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"compute": "https://flaky-vhi.example/compute/v2.1"}}
+	compute := NewClient(tok, WithTransport(rt)).Compute()
+	ctx := context.Background()
+
+	if _, err := compute.GetServer(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	docStatus = 200
+	if _, err := compute.GetServer(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0] != maxMicroversion.String() || sent[1] != "2.60" {
+		t.Errorf("sent microversions %q, want %s then 2.60", sent, maxMicroversion)
+	}
+}
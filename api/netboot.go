@@ -23,13 +23,13 @@ func UpdateNetworkInstall(computeURL, token, vmID string, enabled bool) error {
 		},
 	}
 
-	resp, err := callPOST(url, token, request)
+	resp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to update network_install: %v", err)
 	}
 
 	if resp.ResponseCode != 200 {
-		return fmt.Errorf("failed to update network_install: %s", resp.Response)
+		return resp.fail("failed to update network_install")
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -61,17 +62,25 @@ type AttachNetworkResponse struct {
 	} `json:"interfaceAttachment"`
 }
 
-// ListNetworks fetches the list of networks available to the project.
-func ListNetworks(baseURL, token string, queryParams map[string]string) (struct {
+// NetworkListResponse represents the response for listing networks.
+type NetworkListResponse struct {
 	Networks []Network `json:"networks"`
-}, error) {
-	//var result NetworkListResponse
-	var result struct {
-		Networks []Network `json:"networks"`
-	}
+}
+
+// ListNetworks fetches the list of networks available to the project.
+func ListNetworks(baseURL, token string, queryParams map[string]string) (NetworkListResponse, error) {
+	return networkAt(baseURL, token).ListNetworks(context.Background(), queryParams)
+}
+
+// ListNetworks fetches the list of networks available to the project.
+func (s *NetworkService) ListNetworks(ctx context.Context, queryParams map[string]string) (NetworkListResponse, error) {
+	var result NetworkListResponse
 
 	// Construct the request URL with query parameters.
-	baseURL += "/v2.0/networks"
+	baseURL, err := s.url("/v2.0/networks")
+	if err != nil {
+		return result, err
+	}
 	if len(queryParams) > 0 {
 		params := url.Values{}
 		for key, value := range queryParams {
@@ -81,14 +90,14 @@ func ListNetworks(baseURL, token string, queryParams map[string]string) (struct
 	}
 
 	// Send a GET request to fetch the networks.
-	apiResp, err := callGET(baseURL, token)
+	apiResp, err := s.call(ctx, "GET", baseURL, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch networks: %v", err)
 	}
 
 	// Check for a successful response code.
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list networks request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list networks request failed")
 	}
 
 	// Parse the JSON response.
@@ -124,13 +133,13 @@ func AttachNetworkToVM(networkURL, computeURL, token, vmID, networkID, portID st
 		}
 	}
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return result, fmt.Errorf("failed to attach network: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("attach network request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("attach network request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -142,16 +151,16 @@ func AttachNetworkToVM(networkURL, computeURL, token, vmID, networkID, portID st
 }
 
 // DetachNetworkFromVM detaches a network interface from a VM.
-func DetachNetworkFromVM(networkURL, token, vmID, portID string) error {
-	url := fmt.Sprintf("%s/servers/%s/os-interface/%s", networkURL, vmID, portID)
+func DetachNetworkFromVM(computeURL, token, vmID, portID string) error {
+	url := fmt.Sprintf("%s/servers/%s/os-interface/%s", computeURL, vmID, portID)
 
-	apiResp, err := callDELETE(url, token)
+	apiResp, err := callNova(computeURL, token, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to detach network: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("detach network request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("detach network request failed")
 	}
 
 	return nil
@@ -171,7 +180,7 @@ func GetSubnetDetails(networkURL, token, subnetID string) (Subnet, error) {
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return wrapper.Subnet, fmt.Errorf("get subnet details request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return wrapper.Subnet, apiResp.fail("get subnet details request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &wrapper)
@@ -216,13 +225,13 @@ func GetNetworkIDByName(networkURL, token, networkName string) (string, error) {
 		return networkName, nil
 	}
 
-	networks, err := ListNetworks(networkURL, token, nil)
+	networks, err := networkAt(networkURL, token).NetworkPager(nil, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	foundNetworks := []Network{}
-	for _, network := range networks.Networks {
+	for _, network := range networks {
 		if network.Name == networkName {
 			foundNetworks = append(foundNetworks, network)
 		}
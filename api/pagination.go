@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPageSize is the page size used when walking a whole collection,
+// e.g. to resolve a name.
+const DefaultPageSize = 100
+
+// Pager walks a paginated collection one page at a time, following the next
+// links each service returns. Glance puts the link in "next"; Nova, Neutron
+// and Cinder in "<collection>_links". Either way it carries a marker.
+//
+//	pager := client.Compute().ServerDetailPager(nil, 0)
+//	for pager.More() {
+//		servers, err := pager.NextPage(ctx)
+//		...
+//	}
+type Pager[T any] struct {
+	svc        service
+	path       string
+	collection string
+	params     map[string]string
+	marker     string
+	done       bool
+
+	// check, if set, runs before the first page is fetched.
+	check func(ctx context.Context) error
+}
+
+// newPager returns a pager for the collection at path. A pageSize of 0
+// leaves the page size to the server, or to a "limit" in queryParams.
+func newPager[T any](svc service, path, collection string, queryParams map[string]string, pageSize int) *Pager[T] {
+	params := make(map[string]string, len(queryParams)+1)
+	for k, v := range queryParams {
+		params[k] = v
+	}
+	if pageSize > 0 {
+		params["limit"] = strconv.Itoa(pageSize)
+	}
+	return &Pager[T]{svc: svc, path: path, collection: collection, params: params}
+}
+
+// More reports whether there may be another page to fetch.
+func (p *Pager[T]) More() bool {
+	return !p.done
+}
+
+// NextPage fetches the next page. After the last page More returns false.
+func (p *Pager[T]) NextPage(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+	if p.check != nil {
+		if err := p.check(ctx); err != nil {
+			return nil, err
+		}
+		p.check = nil
+	}
+
+	base, err := p.svc.url(p.path)
+	if err != nil {
+		return nil, err
+	}
+	pageURL, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %v", err)
+	}
+	query := pageURL.Query()
+	for key, value := range p.params {
+		query.Set(key, value)
+	}
+	if p.marker != "" {
+		query.Set("marker", p.marker)
+	}
+	pageURL.RawQuery = query.Encode()
+
+	apiResp, err := p.svc.call(ctx, "GET", pageURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", p.collection, err)
+	}
+	if apiResp.ResponseCode != 200 {
+		return nil, apiResp.fail(fmt.Sprintf("list %s request failed", p.collection))
+	}
+
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(apiResp.Response), &page); err != nil {
+		return nil, fmt.Errorf("failed to parse %s list response: %v", p.collection, err)
+	}
+	var items []T
+	if data, ok := page[p.collection]; ok {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("failed to parse %s list response: %v", p.collection, err)
+		}
+	}
+
+	marker := markerFromLink(nextLink(page, p.collection))
+	if len(items) == 0 || marker == "" || marker == p.marker {
+		p.done = true
+	} else {
+		p.marker = marker
+	}
+	return items, nil
+}
+
+// All fetches the remaining pages and returns their items.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for p.More() {
+		items, err := p.NextPage(ctx)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// nextLink returns the link to the page after this one, if any.
+func nextLink(page map[string]json.RawMessage, collection string) string {
+	if data, ok := page["next"]; ok {
+		var next string
+		if json.Unmarshal(data, &next) == nil && next != "" {
+			return next
+		}
+	}
+	if data, ok := page[collection+"_links"]; ok {
+		var links []struct {
+			Href string `json:"href"`
+			Rel  string `json:"rel"`
+		}
+		if json.Unmarshal(data, &links) == nil {
+			for _, link := range links {
+				if link.Rel == "next" {
+					return link.Href
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// markerFromLink extracts the marker query parameter from a next link.
+func markerFromLink(link string) string {
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("marker")
+}
+
+// ServerPager pages through /servers.
+func (s *ComputeService) ServerPager(queryParams map[string]string, pageSize int) *Pager[VM] {
+	p := newPager[VM](s.service, "/servers", "servers", queryParams, pageSize)
+	p.check = func(ctx context.Context) error { return s.checkServerQuery(ctx, queryParams) }
+	return p
+}
+
+// ServerDetailPager pages through /servers/detail.
+func (s *ComputeService) ServerDetailPager(queryParams map[string]string, pageSize int) *Pager[VMDetail] {
+	p := newPager[VMDetail](s.service, "/servers/detail", "servers", queryParams, pageSize)
+	p.check = func(ctx context.Context) error { return s.checkServerQuery(ctx, queryParams) }
+	return p
+}
+
+// FlavorPager pages through /flavors.
+func (s *ComputeService) FlavorPager(queryParams map[string]string, pageSize int) *Pager[Flavor] {
+	return newPager[Flavor](s.service, "/flavors", "flavors", queryParams, pageSize)
+}
+
+// ImagePager pages through /v2/images.
+func (s *ImageService) ImagePager(queryParams map[string]string, pageSize int) *Pager[Image] {
+	return newPager[Image](s.service, "/v2/images", "images", queryParams, pageSize)
+}
+
+// NetworkPager pages through /v2.0/networks.
+func (s *NetworkService) NetworkPager(queryParams map[string]string, pageSize int) *Pager[Network] {
+	return newPager[Network](s.service, "/v2.0/networks", "networks", queryParams, pageSize)
+}
+
+// PortPager pages through /v2.0/ports.
+func (s *NetworkService) PortPager(queryParams map[string]string, pageSize int) *Pager[Port] {
+	return newPager[Port](s.service, "/v2.0/ports", "ports", queryParams, pageSize)
+}
+
+// VolumePager pages through /volumes/detail.
+func (s *VolumeService) VolumePager(queryParams map[string]string, pageSize int) *Pager[Volume] {
+	return newPager[Volume](s.service, "/volumes/detail", "volumes", queryParams, pageSize)
+}
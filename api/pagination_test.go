@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestPagerFollowsCollectionLinks(t *testing.T) {
+	var markers []string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if got := r.URL.Query().Get("limit"); got != "2" {
+			t.Errorf("limit = %q, want 2", got)
+		}
+		marker := r.URL.Query().Get("marker")
+		markers = append(markers, marker)
+		switch marker {
+		case "":
+			return jsonResponse(200, `{"servers":[{"id":"a"},{"id":"b"}],
+				"servers_links":[{"rel":"next","href":"https://vhi.example/compute/v2.1/servers?limit=2&marker=b"}]}`), nil
+		case "b":
+			return jsonResponse(200, `{"servers":[{"id":"c"}]}`), nil
+		}
+		t.Fatalf("unexpected marker %q", marker)
+		return nil, nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"compute": "https://vhi.example/compute/v2.1"}}
+	pager := NewClient(tok, WithTransport(rt)).Compute().ServerPager(nil, 2)
+
+	servers, err := pager.All(context.Background())
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(servers) != 3 || servers[2].ID != "c" {
+		t.Errorf("unexpected servers %+v", servers)
+	}
+	if len(markers) != 2 || markers[1] != "b" {
+		t.Errorf("unexpected markers %v", markers)
+	}
+	if pager.More() {
+		t.Error("pager should be exhausted")
+	}
+}
+
+func TestPagerFollowsGlanceNext(t *testing.T) {
+	calls := 0
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if r.URL.Query().Get("marker") == "" {
+			return jsonResponse(200, `{"images":[{"id":"img-1"}],"next":"/v2/images?marker=img-1"}`), nil
+		}
+		return jsonResponse(200, `{"images":[{"id":"img-2"}],"first":"/v2/images"}`), nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"image": "https://vhi.example/image"}}
+	pager := NewClient(tok, WithTransport(rt)).Image().ImagePager(nil, 0)
+
+	first, err := pager.NextPage(context.Background())
+	if err != nil {
+		t.Fatalf("NextPage: %v", err)
+	}
+	if len(first) != 1 || !pager.More() {
+		t.Fatalf("expected another page after %+v", first)
+	}
+	rest, err := pager.All(context.Background())
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != "img-2" || calls != 2 {
+		t.Errorf("unexpected result %+v after %d calls", rest, calls)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+)
+
+// Defaults used by Poller and the Wait* helpers when no value is given.
+const (
+	DefaultWaitTimeout  = 30 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+)
+
+// Poller checks a resource until its status reaches one of Target.
+type Poller[T any] struct {
+	// What names the resource in errors, e.g. "VM 1234".
+	What string
+
+	// Get fetches the resource and returns it with its current status.
+	Get func(ctx context.Context) (T, string, error)
+
+	// Target lists the statuses that end the wait successfully, and Failure
+	// those that end it with an error. Both are compared case-insensitively.
+	Target  []string
+	Failure []string
+
+	// Interval is the pause between checks and Timeout bounds the whole
+	// wait. Zero means DefaultPollInterval and DefaultWaitTimeout.
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// Retry, if set, reports whether an error from Get is transient (e.g. a
+	// 404 right after creation) so polling continues. Other errors stop it.
+	Retry func(err error) bool
+
+	// OnProgress is called with each new status as it is first seen.
+	OnProgress func(status string)
+}
+
+// TimeoutError is returned when a Poller's timeout expires before the
+// resource reaches a target status.
+type TimeoutError struct {
+	What       string
+	Target     []string
+	LastStatus string
+	After      time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("timed out after %s waiting for %s to become %s", e.After, e.What, strings.Join(e.Target, " or "))
+	if e.LastStatus != "" {
+		msg += fmt.Sprintf(" (last status %s)", e.LastStatus)
+	}
+	return msg
+}
+
+func (e *TimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// IsTimeout reports whether err is, or wraps, a *TimeoutError.
+func IsTimeout(err error) bool {
+	var te *TimeoutError
+	return errors.As(err, &te)
+}
+
+// Wait polls until the resource reaches a target or failure status, the
+// timeout expires, or ctx is done. Cancellation of ctx is returned as
+// ctx.Err(); only the poller's own timeout produces a *TimeoutError.
+func (p Poller[T]) Wait(ctx context.Context) (T, error) {
+	var zero T
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var last string
+	timedOut := func() error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &TimeoutError{What: p.What, Target: p.Target, LastStatus: last, After: timeout}
+	}
+
+	for {
+		res, status, err := p.Get(wctx)
+		switch {
+		case wctx.Err() != nil:
+			return zero, timedOut()
+		case err != nil && (p.Retry == nil || !p.Retry(err)):
+			return zero, err
+		case err == nil:
+			if status != last {
+				last = status
+				if p.OnProgress != nil {
+					p.OnProgress(status)
+				}
+			}
+			if containsFold(p.Target, status) {
+				return res, nil
+			}
+			if containsFold(p.Failure, status) {
+				return zero, fmt.Errorf("%s entered status %s while waiting for %s", p.What, status, strings.Join(p.Target, " or "))
+			}
+		}
+
+		if err := httpclient.SleepContext(wctx, interval); err != nil {
+			return zero, timedOut()
+		}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitOptions tunes the Wait* helpers and the actions that wait for their
+// result. Zero values use the defaults.
+type WaitOptions struct {
+	Timeout    time.Duration
+	Interval   time.Duration
+	OnProgress func(status string)
+
+	// NoWait makes actions such as StopVM return once the request has been
+	// accepted instead of waiting for it to complete.
+	NoWait bool
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollerReachesTarget(t *testing.T) {
+	statuses := []string{"", "queued", "saving", "saving", "active"}
+	calls := 0
+	var seen []string
+
+	got, err := Poller[int]{
+		What: "image x",
+		Get: func(ctx context.Context) (int, string, error) {
+			calls++
+			if calls == 1 {
+				return 0, "", &Error{StatusCode: 404}
+			}
+			return calls, statuses[calls-1], nil
+		},
+		Target:     []string{"ACTIVE"},
+		Failure:    []string{"killed"},
+		Interval:   time.Millisecond,
+		Retry:      IsNotFound,
+		OnProgress: func(status string) { seen = append(seen, status) },
+	}.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 5 {
+		t.Errorf("got result %d, want 5", got)
+	}
+	if len(seen) != 3 || seen[0] != "queued" || seen[2] != "active" {
+		t.Errorf("progress reported %v", seen)
+	}
+}
+
+func TestPollerFailureAndTimeout(t *testing.T) {
+	get := func(status string) func(context.Context) (struct{}, string, error) {
+		return func(context.Context) (struct{}, string, error) { return struct{}{}, status, nil }
+	}
+
+	_, err := Poller[struct{}]{What: "VM x", Get: get("ERROR"), Target: []string{"ACTIVE"}, Failure: []string{"ERROR"}}.Wait(context.Background())
+	if err == nil || IsTimeout(err) {
+		t.Errorf("expected a failure error, got %v", err)
+	}
+
+	_, err = Poller[struct{}]{What: "VM x", Get: get("BUILD"), Target: []string{"ACTIVE"}, Interval: time.Millisecond, Timeout: 20 * time.Millisecond}.Wait(context.Background())
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.LastStatus != "BUILD" {
+		t.Fatalf("expected a timeout with last status BUILD, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout does not wrap context.DeadlineExceeded: %v", err)
+	}
+}
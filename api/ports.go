@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -73,7 +74,7 @@ func CreatePort(baseURL, token string, networkID, macAddress, name string, fixed
 	}
 
 	if apiResp.ResponseCode != 201 {
-		return result, fmt.Errorf("create port request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("create port request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -121,7 +122,7 @@ func UpdatePort(baseURL, token, portID string, fields PortUpdateFields) (Port, e
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return wrapper.Port, fmt.Errorf("update port request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return wrapper.Port, apiResp.fail("update port request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &wrapper)
@@ -134,9 +135,17 @@ func UpdatePort(baseURL, token, portID string, fields PortUpdateFields) (Port, e
 
 // ListPorts fetches list of ports with optional query parameters
 func ListPorts(baseURL, token string, queryParams map[string]string) (PortListResponse, error) {
+	return networkAt(baseURL, token).ListPorts(context.Background(), queryParams)
+}
+
+// ListPorts fetches list of ports with optional query parameters
+func (s *NetworkService) ListPorts(ctx context.Context, queryParams map[string]string) (PortListResponse, error) {
 	var result PortListResponse
 
-	url := fmt.Sprintf("%s/v2.0/ports", baseURL)
+	url, err := s.url("/v2.0/ports")
+	if err != nil {
+		return result, err
+	}
 	if len(queryParams) > 0 {
 		url += "?"
 		for key, value := range queryParams {
@@ -145,13 +154,13 @@ func ListPorts(baseURL, token string, queryParams map[string]string) (PortListRe
 		url = strings.TrimSuffix(url, "&") // Remove trailing &
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to list ports: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list ports request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list ports request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -164,19 +173,27 @@ func ListPorts(baseURL, token string, queryParams map[string]string) (PortListRe
 
 // GetPortDetails fetches details of a specific port by ID
 func GetPortDetails(baseURL, token, portID string) (Port, error) {
+	return networkAt(baseURL, token).GetPort(context.Background(), portID)
+}
+
+// GetPort fetches details of a specific port by ID
+func (s *NetworkService) GetPort(ctx context.Context, portID string) (Port, error) {
 	var wrapper struct {
 		Port Port `json:"port"`
 	}
 
-	url := fmt.Sprintf("%s/v2.0/ports/%s", baseURL, portID)
+	url, err := s.url("/v2.0/ports/%s", portID)
+	if err != nil {
+		return wrapper.Port, err
+	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return wrapper.Port, fmt.Errorf("failed to fetch port details: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return wrapper.Port, fmt.Errorf("get port details request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return wrapper.Port, apiResp.fail("get port details request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &wrapper)
@@ -189,15 +206,23 @@ func GetPortDetails(baseURL, token, portID string) (Port, error) {
 
 // DeletePort deletes a port by ID
 func DeletePort(baseURL, token, portID string) error {
-	url := fmt.Sprintf("%s/v2.0/ports/%s", baseURL, portID)
+	return networkAt(baseURL, token).DeletePort(context.Background(), portID)
+}
+
+// DeletePort deletes a port by ID
+func (s *NetworkService) DeletePort(ctx context.Context, portID string) error {
+	url, err := s.url("/v2.0/ports/%s", portID)
+	if err != nil {
+		return err
+	}
 
-	apiResp, err := callDELETE(url, token)
+	apiResp, err := s.call(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete port: %v", err)
 	}
 
 	if apiResp.ResponseCode != 204 {
-		return fmt.Errorf("delete port request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("delete port request failed")
 	}
 
 	return nil
@@ -211,18 +236,18 @@ func GetPortIDByName(baseURL, token, portName string) (string, error) {
 	}
 
 	queryParams := map[string]string{"name": portName}
-	resp, err := ListPorts(baseURL, token, queryParams)
+	ports, err := networkAt(baseURL, token).PortPager(queryParams, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
-	if len(resp.Ports) == 0 {
+	if len(ports) == 0 {
 		return "", fmt.Errorf("no port found with name %s", portName)
 	}
 
-	if len(resp.Ports) > 1 {
+	if len(ports) > 1 {
 		return "", fmt.Errorf("multiple ports found with name %s", portName)
 	}
 
-	return resp.Ports[0].ID, nil
+	return ports[0].ID, nil
 }
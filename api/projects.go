@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -18,15 +19,25 @@ type ProjectListResponse struct {
 
 // ListProjects calls GET /v3/projects using the token for authentication.
 func ListProjects(identityUrl, token string) (ProjectListResponse, error) {
+	return identityAt(identityUrl, token).ListProjects(context.Background())
+}
+
+// ListProjects calls GET /v3/projects.
+func (s *IdentityService) ListProjects(ctx context.Context) (ProjectListResponse, error) {
 	var result ProjectListResponse
 
-	apiResp, err := callGET(fmt.Sprintf("%s/projects", identityUrl), token)
+	url, err := s.url("/projects")
+	if err != nil {
+		return result, err
+	}
+
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to list projects: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list projects failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list projects failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -63,7 +74,7 @@ func GetProjectIDByName(identityUrl, token, projectName string) (string, error)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return "", fmt.Errorf("get project ID failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return "", apiResp.fail("get project ID failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
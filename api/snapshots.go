@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CreateVMSnapshot asks Nova to snapshot a VM and returns the ID of the new
+// image.
+func CreateVMSnapshot(computeURL, token, vmID, name string, metadata map[string]string) (string, error) {
+	return computeAt(computeURL, token).CreateServerImage(context.Background(), vmID, name, metadata)
+}
+
+// CreateServerImage asks Nova to snapshot a VM and returns the ID of the new
+// image. For a volume-backed VM Nova snapshots every attached volume and
+// records the snapshots in the image's block device mapping.
+func (s *ComputeService) CreateServerImage(ctx context.Context, vmID, name string, metadata map[string]string) (string, error) {
+	// Older microversions only return the image in a Location header
+	if err := s.require(ctx, "snapshotting a VM", createImageIDVersion); err != nil {
+		return "", err
+	}
+
+	url, err := s.url("/servers/%s/action", vmID)
+	if err != nil {
+		return "", err
+	}
+	createImage := map[string]interface{}{"name": name}
+	if len(metadata) > 0 {
+		createImage["metadata"] = metadata
+	}
+	request := map[string]interface{}{"createImage": createImage}
+
+	resp, err := s.call(ctx, "POST", url, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to send snapshot request: %v", err)
+	}
+	if resp.ResponseCode != 202 {
+		return "", resp.fail("snapshot request failed")
+	}
+
+	var result struct {
+		ImageID string `json:"image_id"`
+	}
+	if err := json.Unmarshal([]byte(resp.Response), &result); err != nil {
+		return "", fmt.Errorf("failed to parse snapshot response: %v", err)
+	}
+	return result.ImageID, nil
+}
+
+// ListVMSnapshots returns the snapshots of a VM, newest first.
+func (s *ImageService) ListVMSnapshots(ctx context.Context, vmID string) ([]VMSnapshot, error) {
+	params := map[string]string{"instance_uuid": vmID, "sort": "created_at:desc"}
+	snaps, err := newPager[VMSnapshot](s.service, "/v2/images", "images", params, DefaultPageSize).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Timestamps are RFC 3339, so they sort as strings
+	sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].CreatedAt > snaps[j].CreatedAt })
+	return snaps, nil
+}
+
+// GetVMSnapshot fetches a snapshot image.
+func (s *ImageService) GetVMSnapshot(ctx context.Context, imageID string) (VMSnapshot, error) {
+	var result VMSnapshot
+
+	url, err := s.url("/v2/images/%s", imageID)
+	if err != nil {
+		return result, err
+	}
+	apiResp, err := s.call(ctx, "GET", url, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to get image details: %v", err)
+	}
+	if apiResp.ResponseCode != 200 {
+		return result, apiResp.fail("image details request failed")
+	}
+	if err := json.Unmarshal([]byte(apiResp.Response), &result); err != nil {
+		return result, fmt.Errorf("failed to parse image details: %v", err)
+	}
+	return result, nil
+}
+
+// VolumeBacked reports whether the snapshot is of a volume-backed VM, so
+// its data lives in volume snapshots rather than the image.
+func (v VMSnapshot) VolumeBacked() bool {
+	return v.BlockDeviceMapping != ""
+}
+
+// VolumeSnapshotIDs returns the Cinder snapshots listed in the snapshot's
+// block device mapping.
+func (v VMSnapshot) VolumeSnapshotIDs() ([]string, error) {
+	if !v.VolumeBacked() {
+		return nil, nil
+	}
+	var bdm []struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := json.Unmarshal([]byte(v.BlockDeviceMapping), &bdm); err != nil {
+		return nil, fmt.Errorf("failed to parse block device mapping of image %s: %v", v.ID, err)
+	}
+	var ids []string
+	for _, m := range bdm {
+		if m.SnapshotID != "" {
+			ids = append(ids, m.SnapshotID)
+		}
+	}
+	return ids, nil
+}
+
+// GetVolumeSnapshot fetches a volume snapshot.
+func (s *VolumeService) GetVolumeSnapshot(ctx context.Context, snapshotID string) (VolumeSnapshot, error) {
+	var wrapper struct {
+		Snapshot VolumeSnapshot `json:"snapshot"`
+	}
+
+	url, err := s.url("/snapshots/%s", snapshotID)
+	if err != nil {
+		return wrapper.Snapshot, err
+	}
+	resp, err := s.call(ctx, "GET", url, nil)
+	if err != nil {
+		return wrapper.Snapshot, fmt.Errorf("failed to get volume snapshot: %v", err)
+	}
+	if resp.ResponseCode != 200 {
+		return wrapper.Snapshot, resp.fail("volume snapshot request failed")
+	}
+	if err := json.Unmarshal([]byte(resp.Response), &wrapper); err != nil {
+		return wrapper.Snapshot, fmt.Errorf("failed to parse volume snapshot: %v", err)
+	}
+	return wrapper.Snapshot, nil
+}
+
+// DeleteVolumeSnapshot deletes a volume snapshot.
+func (s *VolumeService) DeleteVolumeSnapshot(ctx context.Context, snapshotID string) error {
+	url, err := s.url("/snapshots/%s", snapshotID)
+	if err != nil {
+		return err
+	}
+	resp, err := s.call(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete volume snapshot: %v", err)
+	}
+	if resp.ResponseCode != 202 {
+		return resp.fail("failed to delete volume snapshot")
+	}
+	return nil
+}
+
+// WaitForVolumeSnapshotStatus waits until a volume snapshot reaches the
+// target status, failing if it goes to error.
+func WaitForVolumeSnapshotStatus(ctx context.Context, storageURL, token, snapshotID, targetStatus string, opts WaitOptions) error {
+	_, err := Poller[VolumeSnapshot]{
+		What: "volume snapshot " + snapshotID,
+		Get: func(ctx context.Context) (VolumeSnapshot, string, error) {
+			snap, err := volumeAt(storageURL, token).GetVolumeSnapshot(ctx, snapshotID)
+			return snap, snap.Status, err
+		},
+		Target:     []string{targetStatus},
+		Failure:    []string{"error", "error_deleting"},
+		Interval:   opts.Interval,
+		Timeout:    opts.Timeout,
+		OnProgress: opts.OnProgress,
+	}.Wait(ctx)
+	return err
+}
+
+// WaitForVMSnapshot waits until a VM snapshot is usable: the image is
+// active or, for a volume-backed VM, every volume snapshot is available.
+func WaitForVMSnapshot(ctx context.Context, imageURL, storageURL, token, imageID string, opts WaitOptions) (VMSnapshot, error) {
+	snap, err := imageAt(imageURL, token).GetVMSnapshot(ctx, imageID)
+	if err != nil {
+		return snap, err
+	}
+	if !snap.VolumeBacked() {
+		if _, err := WaitForImageStatus(ctx, imageURL, token, imageID, "active", opts); err != nil {
+			return snap, err
+		}
+		return imageAt(imageURL, token).GetVMSnapshot(ctx, imageID)
+	}
+
+	ids, err := snap.VolumeSnapshotIDs()
+	if err != nil {
+		return snap, err
+	}
+	for _, id := range ids {
+		if err := WaitForVolumeSnapshotStatus(ctx, storageURL, token, id, "available", opts); err != nil {
+			return snap, err
+		}
+	}
+	return snap, nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestListVMSnapshots(t *testing.T) {
+	var query string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		query = r.URL.RawQuery
+		return jsonResponse(200, `{"images":[
+			{"id":"old","name":"web-1-a","status":"active","created_at":"2025-01-01T02:00:00Z","instance_uuid":"vm1"},
+			{"id":"new","name":"web-1-b","status":"active","created_at":"2025-01-02T02:00:00Z","instance_uuid":"vm1",
+			 "block_device_mapping":"[{\"boot_index\": 0, \"snapshot_id\": \"s1\"}, {\"boot_index\": null, \"snapshot_id\": \"s2\"}]"}
+		]}`), nil
+	})
+
+	tok := Token{Value: "tok123", Endpoints: map[string]string{"image": "https://vhi.example/image"}}
+	snaps, err := NewClient(tok, WithTransport(rt)).Image().ListVMSnapshots(context.Background(), "vm1")
+	if err != nil {
+		t.Fatalf("ListVMSnapshots: %v", err)
+	}
+	if query != "instance_uuid=vm1&limit=100&sort=created_at%3Adesc" {
+		t.Errorf("unexpected query %q", query)
+	}
+	if len(snaps) != 2 || snaps[0].ID != "new" {
+		t.Fatalf("expected newest first, got %+v", snaps)
+	}
+
+	if snaps[1].VolumeBacked() {
+		t.Errorf("image-backed snapshot reported as volume-backed")
+	}
+	ids, err := snaps[0].VolumeSnapshotIDs()
+	if err != nil || len(ids) != 2 || ids[0] != "s1" || ids[1] != "s2" {
+		t.Errorf("VolumeSnapshotIDs() = %v, %v", ids, err)
+	}
+}
@@ -64,13 +64,13 @@ func GetTenantUsage(computeURL, token string, start, end time.Time, detailed boo
 		url += "detailed=1"
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch tenant usage: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("tenant usage request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("tenant usage request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -97,13 +97,13 @@ func GetSingleTenantUsage(computeURL, token, tenantID string, start, end time.Ti
 		url += fmt.Sprintf("end=%s", end.Format(time.RFC3339))
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch tenant usage: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("tenant usage request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("tenant usage request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
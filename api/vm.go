@@ -1,23 +1,24 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
-	"time"
+	neturl "net/url"
 )
 
 // UpdateVM updates basic VM properties like name and description
 func UpdateVM(computeURL, token, vmID string, request UpdateVMRequest) error {
 	url := fmt.Sprintf("%s/servers/%s", computeURL, vmID)
 
-	apiResp, err := callPUT(url, token, request)
+	apiResp, err := callNova(computeURL, token, "PUT", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to update VM: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("update VM failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("update VM failed")
 	}
 
 	return nil
@@ -39,13 +40,13 @@ func UpdateVMMetadata(computeURL, token, vmID string, metadata map[string]string
 		Metadata: metadata,
 	}
 
-	apiResp, err := callPUT(url, token, request)
+	apiResp, err := callNova(computeURL, token, "PUT", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to update VM metadata: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("update metadata failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("update metadata failed")
 	}
 
 	return nil
@@ -58,13 +59,13 @@ func UpdateVMMetadataItem(computeURL, token, vmID, key, value string) error {
 	request := UpdateMetadataItemRequest{}
 	request.Meta.Value = value
 
-	apiResp, err := callPUT(url, token, request)
+	apiResp, err := callNova(computeURL, token, "PUT", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to update VM metadata item: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("update metadata item failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("update metadata item failed")
 	}
 
 	return nil
@@ -74,13 +75,13 @@ func UpdateVMMetadataItem(computeURL, token, vmID, key, value string) error {
 func DeleteVMMetadataItem(computeURL, token, vmID, key string) error {
 	url := fmt.Sprintf("%s/servers/%s/metadata/%s", computeURL, vmID, key)
 
-	apiResp, err := callDELETE(url, token)
+	apiResp, err := callNova(computeURL, token, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete VM metadata item: %v", err)
 	}
 
 	if apiResp.ResponseCode != 204 {
-		return fmt.Errorf("delete metadata item failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("delete metadata item failed")
 	}
 
 	return nil
@@ -94,13 +95,13 @@ func GetVMMetadata(computeURL, token, vmID string) (map[string]string, error) {
 
 	url := fmt.Sprintf("%s/servers/%s/metadata", computeURL, vmID)
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get VM metadata: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return nil, fmt.Errorf("get metadata failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return nil, apiResp.fail("get metadata failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -111,6 +112,107 @@ func GetVMMetadata(computeURL, token, vmID string) (map[string]string, error) {
 	return result.Metadata, nil
 }
 
+// GetVMTags lists the tags on a VM.
+func GetVMTags(computeURL, token, vmID string) ([]string, error) {
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+
+	if err := computeAt(computeURL, token).require(context.Background(), "server tags", serverTagsVersion); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/servers/%s/tags", computeURL, vmID)
+
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VM tags: %v", err)
+	}
+
+	if apiResp.ResponseCode != 200 {
+		return nil, apiResp.fail("get tags failed")
+	}
+
+	err = json.Unmarshal([]byte(apiResp.Response), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tags response: %v", err)
+	}
+
+	return result.Tags, nil
+}
+
+// SetVMTags replaces all tags on a VM and returns the new set.
+func SetVMTags(computeURL, token, vmID string, tags []string) ([]string, error) {
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+
+	if err := computeAt(computeURL, token).require(context.Background(), "server tags", serverTagsVersion); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/servers/%s/tags", computeURL, vmID)
+
+	if tags == nil {
+		tags = []string{}
+	}
+	request := struct {
+		Tags []string `json:"tags"`
+	}{Tags: tags}
+
+	apiResp, err := callNova(computeURL, token, "PUT", url, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set VM tags: %v", err)
+	}
+
+	if apiResp.ResponseCode != 200 {
+		return nil, apiResp.fail("set tags failed")
+	}
+
+	err = json.Unmarshal([]byte(apiResp.Response), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tags response: %v", err)
+	}
+
+	return result.Tags, nil
+}
+
+// AddVMTag adds a single tag to a VM. Adding a tag it already has is a no-op.
+func AddVMTag(computeURL, token, vmID, tag string) error {
+	if err := computeAt(computeURL, token).require(context.Background(), "server tags", serverTagsVersion); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/servers/%s/tags/%s", computeURL, vmID, neturl.PathEscape(tag))
+
+	apiResp, err := callNova(computeURL, token, "PUT", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add VM tag: %v", err)
+	}
+
+	if apiResp.ResponseCode != 201 && apiResp.ResponseCode != 204 {
+		return apiResp.fail("add tag failed")
+	}
+
+	return nil
+}
+
+// RemoveVMTag removes a single tag from a VM.
+func RemoveVMTag(computeURL, token, vmID, tag string) error {
+	if err := computeAt(computeURL, token).require(context.Background(), "server tags", serverTagsVersion); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/servers/%s/tags/%s", computeURL, vmID, neturl.PathEscape(tag))
+
+	apiResp, err := callNova(computeURL, token, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove VM tag: %v", err)
+	}
+
+	if apiResp.ResponseCode != 204 {
+		return apiResp.fail("remove tag failed")
+	}
+
+	return nil
+}
+
 // ResizeVM changes the flavor of a VM (requires a subsequent confirm or revert)
 func ResizeVM(computeURL, token, vmID, flavorID string) error {
 	url := fmt.Sprintf("%s/servers/%s/action", computeURL, vmID)
@@ -122,13 +224,13 @@ func ResizeVM(computeURL, token, vmID, flavorID string) error {
 	}{}
 	request.Resize.FlavorRef = flavorID
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to resize VM: %v", err)
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("resize failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("resize failed")
 	}
 
 	return nil
@@ -144,13 +246,13 @@ func ConfirmResize(computeURL, token, vmID string) error {
 		ConfirmResize: &struct{}{},
 	}
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to confirm resize: %v", err)
 	}
 
 	if apiResp.ResponseCode != 204 {
-		return fmt.Errorf("confirm resize failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("confirm resize failed")
 	}
 
 	return nil
@@ -166,13 +268,13 @@ func RevertResize(computeURL, token, vmID string) error {
 		RevertResize: &struct{}{},
 	}
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to revert resize: %v", err)
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("revert resize failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("revert resize failed")
 	}
 
 	return nil
@@ -180,23 +282,34 @@ func RevertResize(computeURL, token, vmID string) error {
 
 // ListVMs fetches the list of virtual machines.
 func ListVMs(computeURL, token string, queryParams map[string]string) (VMListResponse, error) {
+	return computeAt(computeURL, token).ListServers(context.Background(), queryParams)
+}
+
+// ListServers fetches the list of virtual machines.
+func (s *ComputeService) ListServers(ctx context.Context, queryParams map[string]string) (VMListResponse, error) {
 	var result VMListResponse
 
-	baseURL := fmt.Sprintf("%s/servers", computeURL)
+	baseURL, err := s.url("/servers")
+	if err != nil {
+		return result, err
+	}
+	if err := s.checkServerQuery(ctx, queryParams); err != nil {
+		return result, err
+	}
 	if len(queryParams) > 0 {
-		baseURL += "?"
+		query := neturl.Values{}
 		for key, value := range queryParams {
-			baseURL += fmt.Sprintf("%s=%s&", key, value)
+			query.Set(key, value)
 		}
-		baseURL = strings.TrimSuffix(baseURL, "&")
+		baseURL += "?" + query.Encode()
 	}
 
-	apiResp, err := callGET(baseURL, token)
+	apiResp, err := s.call(ctx, "GET", baseURL, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch VMs: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("VM list request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM list request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -213,23 +326,34 @@ type VMDetailListResponse struct {
 
 // ListVMsDetail fetches detailed information for all VMs in one call.
 func ListVMsDetail(computeURL, token string, queryParams map[string]string) (VMDetailListResponse, error) {
+	return computeAt(computeURL, token).ListServersDetail(context.Background(), queryParams)
+}
+
+// ListServersDetail fetches detailed information for all VMs in one call.
+func (s *ComputeService) ListServersDetail(ctx context.Context, queryParams map[string]string) (VMDetailListResponse, error) {
 	var result VMDetailListResponse
 
-	baseURL := fmt.Sprintf("%s/servers/detail", computeURL)
+	baseURL, err := s.url("/servers/detail")
+	if err != nil {
+		return result, err
+	}
+	if err := s.checkServerQuery(ctx, queryParams); err != nil {
+		return result, err
+	}
 	if len(queryParams) > 0 {
-		baseURL += "?"
+		query := neturl.Values{}
 		for key, value := range queryParams {
-			baseURL += fmt.Sprintf("%s=%s&", key, value)
+			query.Set(key, value)
 		}
-		baseURL = strings.TrimSuffix(baseURL, "&")
+		baseURL += "?" + query.Encode()
 	}
 
-	apiResp, err := callGET(baseURL, token)
+	apiResp, err := s.call(ctx, "GET", baseURL, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch VM details: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("VM detail list request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM detail list request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -241,17 +365,33 @@ func ListVMsDetail(computeURL, token string, queryParams map[string]string) (VMD
 
 // CreateVM sends a request to create a new VM using callPOST.
 func CreateVM(computeURL, token string, request CreateVMRequest) (CreateVMResponse, error) {
+	return computeAt(computeURL, token).CreateServer(context.Background(), request)
+}
+
+// CreateServer sends a request to create a new VM.
+func (s *ComputeService) CreateServer(ctx context.Context, request CreateVMRequest) (CreateVMResponse, error) {
 	var result CreateVMResponse
 
-	url := fmt.Sprintf("%s/servers", computeURL)
+	url, err := s.url("/servers")
+	if err != nil {
+		return result, err
+	}
+	for _, bdm := range request.Server.BlockDeviceMappingV2 {
+		if _, ok := bdm["volume_type"]; ok {
+			if err := s.require(ctx, "volume_type in block device mappings", bdmVolumeTypeVersion); err != nil {
+				return result, err
+			}
+			break
+		}
+	}
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := s.call(ctx, "POST", url, request)
 	if err != nil {
 		return result, fmt.Errorf("failed to send VM create request: %v", err)
 	}
 
 	if apiResp.ResponseCode != 202 { // 202 Accepted
-		return result, fmt.Errorf("VM create request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM create request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -266,13 +406,20 @@ func CreateVMRaw(computeURL, token string, jsonData []byte) (CreateVMResponse, e
 	var result CreateVMResponse
 	url := fmt.Sprintf("%s/servers", computeURL)
 
-	apiResp, err := callPOST(url, token, json.RawMessage(jsonData))
+	if bytes.Contains(jsonData, []byte(`"volume_type"`)) {
+		err := computeAt(computeURL, token).require(context.Background(), "volume_type in block device mappings", bdmVolumeTypeVersion)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	apiResp, err := callNova(computeURL, token, "POST", url, json.RawMessage(jsonData))
 	if err != nil {
 		return result, fmt.Errorf("error making HTTP POST request: %v", err)
 	}
 
 	if apiResp.ResponseCode != 202 { // 202 Accepted
-		return result, fmt.Errorf("VM create request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM create request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -288,13 +435,13 @@ func GetVMNetworks(computeURL, token, vmID string) (VMNetworkListResponse, error
 
 	url := fmt.Sprintf("%s/servers/%s/os-interface", computeURL, vmID)
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := callNova(computeURL, token, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch VM networks: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("VM networks request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM networks request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -306,17 +453,24 @@ func GetVMNetworks(computeURL, token, vmID string) (VMNetworkListResponse, error
 
 // GetVMDetails fetches detailed information about a specific VM.
 func GetVMDetails(computeURL, token, vmID string) (VMDetail, error) {
+	return computeAt(computeURL, token).GetServer(context.Background(), vmID)
+}
+
+// GetServer fetches detailed information about a specific VM.
+func (s *ComputeService) GetServer(ctx context.Context, vmID string) (VMDetail, error) {
 	var result VMDetail
 
-	url := fmt.Sprintf("%s/servers/%s", computeURL, vmID)
-	apiResp, err := callGET(url, token)
+	url, err := s.url("/servers/%s", vmID)
+	if err != nil {
+		return result, err
+	}
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch VM details: %v", err)
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("VM details request failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("VM details request failed")
 	}
 
 	var wrapper struct {
@@ -331,7 +485,7 @@ func GetVMDetails(computeURL, token, vmID string) (VMDetail, error) {
 	if vm.Flavor.RAM == 0 && vm.Flavor.VCPUs == 0 && vm.Flavor.Disk == 0 {
 		flavorID := vm.Flavor.ID
 		if flavorID != "" {
-			flv, err := GetFlavorDetails(computeURL, token, flavorID)
+			flv, err := s.GetFlavor(ctx, flavorID)
 			if err == nil {
 				vm.Flavor.RAM = flv.Flavor.RAM
 				vm.Flavor.VCPUs = flv.Flavor.VCPUs
@@ -349,44 +503,29 @@ func GetVMDetails(computeURL, token, vmID string) (VMDetail, error) {
 }
 
 // StopVM sends a request to stop a VM and waits for it to be fully stopped
-func StopVM(computeURL, token, vmID string) error {
+func StopVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
 	url := fmt.Sprintf("%s/servers/%s/action", computeURL, vmID)
 
 	// Send the stop request
 	request := ActionRequest{OsStop: &struct{}{}}
 
-	resp, err := callPOST(url, token, request)
+	resp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to send stop request: %v", err)
 	}
 	if resp.ResponseCode != 202 {
-		return fmt.Errorf("stop request failed: %s", resp.Response)
+		return resp.fail("stop request failed")
 	}
 
-	// Poll until shutdown complete or error
-	maxAttempts := 30 // 5 minutes total (10 second intervals)
-	attempts := 0
-	for {
-		if attempts >= maxAttempts {
-			return fmt.Errorf("timeout waiting for VM to stop")
-		}
-		vmDetails, err := GetVMDetails(computeURL, token, vmID)
-		if err != nil {
-			return fmt.Errorf("failed to get VM details while stopping: %v", err)
-		}
-		if vmDetails.Status == "ERROR" {
-			return fmt.Errorf("VM entered error state while stopping")
-		}
-		if vmDetails.Status == "SHUTOFF" {
-			return nil
-		}
-		time.Sleep(10 * time.Second)
-		attempts++
+	if opts.NoWait {
+		return nil
 	}
+	_, err = WaitForStatus(ctx, computeURL, token, vmID, opts, "SHUTOFF")
+	return err
 }
 
 // RebootVM sends a request to perform a reboot (HARD or SOFT) on a VM.
-func RebootVM(computeURL, token, vmID string, rebootType string) error {
+func RebootVM(ctx context.Context, computeURL, token, vmID string, rebootType string, opts WaitOptions) error {
 	// Default to SOFT if none specified
 	if rebootType == "" {
 		rebootType = "SOFT"
@@ -399,34 +538,194 @@ func RebootVM(computeURL, token, vmID string, rebootType string) error {
 	var rebootRequest RebootRequestPayload
 	rebootRequest.Reboot.Type = rebootType
 
-	resp, err := callPOST(url, token, rebootRequest)
+	resp, err := callNova(computeURL, token, "POST", url, rebootRequest)
 	if err != nil {
 		return fmt.Errorf("failed to send reboot request: %v", err)
 	}
 	if resp.ResponseCode != 202 {
-		return fmt.Errorf("reboot request failed: %s", resp.Response)
+		return resp.fail("reboot request failed")
 	}
 
-	// Poll until the VM becomes ACTIVE or timeout
-	maxAttempts := 30
-	attempts := 0
-	for {
-		if attempts >= maxAttempts {
-			return fmt.Errorf("timeout waiting for VM to reboot")
-		}
-		vmDetails, err := GetVMDetails(computeURL, token, vmID)
-		if err != nil {
-			return fmt.Errorf("failed to fetch VM details during reboot: %v", err)
+	if opts.NoWait {
+		return nil
+	}
+	_, err = WaitForStatus(ctx, computeURL, token, vmID, opts, "ACTIVE")
+	return err
+}
+
+// serverAction posts a body to /servers/{id}/action and checks it was
+// accepted. what names the action in errors.
+func serverAction(computeURL, token, vmID, what string, request interface{}) (ApiResponse, error) {
+	url := fmt.Sprintf("%s/servers/%s/action", computeURL, vmID)
+
+	resp, err := callNova(computeURL, token, "POST", url, request)
+	if err != nil {
+		return resp, fmt.Errorf("failed to send %s request: %v", what, err)
+	}
+	if resp.ResponseCode != 200 && resp.ResponseCode != 202 {
+		return resp, resp.fail(what + " request failed")
+	}
+	return resp, nil
+}
+
+// serverActionAndWait runs a server action, then waits for one of target
+// unless opts.NoWait is set.
+func serverActionAndWait(ctx context.Context, computeURL, token, vmID, what string, request interface{}, opts WaitOptions, target ...string) error {
+	if _, err := serverAction(computeURL, token, vmID, what, request); err != nil {
+		return err
+	}
+	if opts.NoWait {
+		return nil
+	}
+	_, err := WaitForStatus(ctx, computeURL, token, vmID, opts, target...)
+	return err
+}
+
+// StartVM starts a stopped VM and waits for it to be ACTIVE.
+func StartVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"os-start": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "start", request, opts, "ACTIVE")
+}
+
+// SuspendVM suspends a VM to disk and waits for it to be SUSPENDED.
+func SuspendVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"suspend": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "suspend", request, opts, "SUSPENDED")
+}
+
+// ResumeVM resumes a suspended VM and waits for it to be ACTIVE.
+func ResumeVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"resume": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "resume", request, opts, "ACTIVE")
+}
+
+// ShelveVM shelves a VM and waits for it to be SHELVED or, once the cloud
+// has offloaded it from its host, SHELVED_OFFLOADED.
+func ShelveVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"shelve": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "shelve", request, opts, "SHELVED", "SHELVED_OFFLOADED")
+}
+
+// UnshelveVM unshelves a VM and waits for it to be ACTIVE.
+func UnshelveVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"unshelve": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "unshelve", request, opts, "ACTIVE")
+}
+
+// RescueVM boots a VM into rescue mode, from imageRef if given, and waits
+// for it to be in RESCUE. It returns the rescue admin password, if any.
+func RescueVM(ctx context.Context, computeURL, token, vmID, imageRef string, opts WaitOptions) (string, error) {
+	var result struct {
+		AdminPass string `json:"adminPass"`
+	}
+
+	rescue := map[string]string{}
+	if imageRef != "" {
+		rescue["rescue_image_ref"] = imageRef
+	}
+	request := map[string]interface{}{"rescue": rescue}
+
+	resp, err := serverAction(computeURL, token, vmID, "rescue", request)
+	if err != nil {
+		return "", err
+	}
+	if resp.Response != "" {
+		if err := json.Unmarshal([]byte(resp.Response), &result); err != nil {
+			return "", fmt.Errorf("failed to parse rescue response: %v", err)
 		}
-		if vmDetails.Status == "ERROR" {
-			return fmt.Errorf("VM entered error state during reboot")
+	}
+	if opts.NoWait {
+		return result.AdminPass, nil
+	}
+	_, err = WaitForStatus(ctx, computeURL, token, vmID, opts, "RESCUE")
+	return result.AdminPass, err
+}
+
+// UnrescueVM leaves rescue mode and waits for the VM to be ACTIVE.
+func UnrescueVM(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	request := map[string]interface{}{"unrescue": nil}
+	return serverActionAndWait(ctx, computeURL, token, vmID, "unrescue", request, opts, "ACTIVE")
+}
+
+// RebuildVM reinstalls a VM from an image, keeping its ID, ports and IPs,
+// and waits for it to come back ACTIVE, or SHUTOFF if it was stopped.
+// Rebuilding a volume-backed VM reimages its boot volume, which needs Nova
+// microversion 2.93; user data needs 2.57.
+func RebuildVM(ctx context.Context, computeURL, token, vmID string, req RebuildVMRequest, opts WaitOptions) error {
+	s := computeAt(computeURL, token)
+
+	rebuild := map[string]interface{}{"imageRef": req.ImageRef}
+	if req.UserData != "" {
+		if err := s.require(ctx, "changing user data on rebuild", rebuildUserDataVersion); err != nil {
+			return err
 		}
-		if vmDetails.Status == "ACTIVE" {
-			return nil
+		rebuild["user_data"] = req.UserData
+	}
+
+	vm, err := s.GetServer(ctx, vmID)
+	if err != nil {
+		return err
+	}
+	rctx := ctx
+	if vm.Image.ID == "" { // Nova reports no image for volume-backed VMs
+		rctx, err = s.withMicroversion(ctx, "rebuilding a volume-backed VM", reimageBootVolumeVersion)
+		if err != nil {
+			return err
 		}
-		time.Sleep(10 * time.Second)
-		attempts++
 	}
+
+	url, err := s.url("/servers/%s/action", vmID)
+	if err != nil {
+		return err
+	}
+	resp, err := s.call(rctx, "POST", url, map[string]interface{}{"rebuild": rebuild})
+	if err != nil {
+		return fmt.Errorf("failed to send rebuild request: %v", err)
+	}
+	if resp.ResponseCode != 202 {
+		return resp.fail("rebuild request failed")
+	}
+
+	if opts.NoWait {
+		return nil
+	}
+	_, err = WaitForStatus(ctx, computeURL, token, vmID, opts, "ACTIVE", "SHUTOFF")
+	return err
+}
+
+// LockVM locks a VM against changes by non-admin users. A reason needs
+// microversion 2.73, which is sent on the lock request only.
+func LockVM(computeURL, token, vmID, reason string) error {
+	if reason == "" {
+		_, err := serverAction(computeURL, token, vmID, "lock", map[string]interface{}{"lock": nil})
+		return err
+	}
+
+	s := computeAt(computeURL, token)
+	ctx, err := s.withMicroversion(context.Background(), "a lock reason", lockedReasonVersion)
+	if err != nil {
+		return err
+	}
+	url, err := s.url("/servers/%s/action", vmID)
+	if err != nil {
+		return err
+	}
+	request := map[string]interface{}{"lock": map[string]string{"locked_reason": reason}}
+	resp, err := s.call(ctx, "POST", url, request)
+	if err != nil {
+		return fmt.Errorf("failed to send lock request: %v", err)
+	}
+	if resp.ResponseCode != 202 {
+		return resp.fail("lock request failed")
+	}
+	return nil
+}
+
+// UnlockVM unlocks a locked VM.
+func UnlockVM(computeURL, token, vmID string) error {
+	request := map[string]interface{}{"unlock": nil}
+	_, err := serverAction(computeURL, token, vmID, "unlock", request)
+	return err
 }
 
 // PauseVM sends a request to pause a VM
@@ -439,12 +738,12 @@ func PauseVM(computeURL, token, vmID string) error {
 		Pause: &struct{}{},
 	}
 
-	resp, err := callPOST(url, token, request)
+	resp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to send pause request: %v", err)
 	}
 	if resp.ResponseCode != 202 {
-		return fmt.Errorf("pause request failed: %s", resp.Response)
+		return resp.fail("pause request failed")
 	}
 
 	return nil
@@ -460,46 +759,83 @@ func UnpauseVM(computeURL, token, vmID string) error {
 		Unpause: &struct{}{},
 	}
 
-	resp, err := callPOST(url, token, request)
+	resp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to send unpause request: %v", err)
 	}
 	if resp.ResponseCode != 202 {
-		return fmt.Errorf("unpause request failed: %s", resp.Response)
+		return resp.fail("unpause request failed")
 	}
 
 	return nil
 }
 
-// WaitForStatus waits for a VM to reach a given status or returns error on timeout/error
-func WaitForStatus(computeURL, token, vmID string, targetStatus string) (VMDetail, error) {
-	maxAttempts := 30
-	for attempts := 0; attempts < maxAttempts; attempts++ {
-		vmDetails, err := GetVMDetails(computeURL, token, vmID)
-		if err != nil {
-			return VMDetail{}, fmt.Errorf("failed to get VM details: %v", err)
-		}
-		if strings.EqualFold(vmDetails.Status, "ERROR") {
-			return VMDetail{}, fmt.Errorf("VM creation failed: status ERROR")
-		}
-		if strings.EqualFold(vmDetails.Status, targetStatus) {
-			return vmDetails, nil
-		}
-		time.Sleep(10 * time.Second)
+// vmStatus is the status a VM is polled on. While a task is running the
+// task state is appended, so e.g. an ACTIVE VM that is still rebooting does
+// not yet count as ACTIVE.
+func vmStatus(vm VMDetail) string {
+	if vm.TaskState != "" {
+		return fmt.Sprintf("%s (%s)", vm.Status, vm.TaskState)
 	}
-	return VMDetail{}, fmt.Errorf("timeout waiting for VM to reach status %q", targetStatus)
+	return vm.Status
+}
+
+// WaitForStatus waits until a VM reaches one of the target statuses with no
+// task in progress, failing if it goes to ERROR.
+func WaitForStatus(ctx context.Context, computeURL, token, vmID string, opts WaitOptions, target ...string) (VMDetail, error) {
+	return Poller[VMDetail]{
+		What: "VM " + vmID,
+		Get: func(ctx context.Context) (VMDetail, string, error) {
+			vm, err := computeAt(computeURL, token).GetServer(ctx, vmID)
+			return vm, vmStatus(vm), err
+		},
+		Target:     target,
+		Failure:    []string{"ERROR"},
+		Interval:   opts.Interval,
+		Timeout:    opts.Timeout,
+		OnProgress: opts.OnProgress,
+	}.Wait(ctx)
+}
+
+// WaitForVMDeleted waits until a VM is gone, failing if it goes to ERROR
+// instead.
+func WaitForVMDeleted(ctx context.Context, computeURL, token, vmID string, opts WaitOptions) error {
+	_, err := Poller[VMDetail]{
+		What: "VM " + vmID,
+		Get: func(ctx context.Context) (VMDetail, string, error) {
+			vm, err := computeAt(computeURL, token).GetServer(ctx, vmID)
+			if IsNotFound(err) {
+				return vm, "DELETED", nil
+			}
+			return vm, vmStatus(vm), err
+		},
+		Target:     []string{"DELETED"},
+		Failure:    []string{"ERROR"},
+		Interval:   opts.Interval,
+		Timeout:    opts.Timeout,
+		OnProgress: opts.OnProgress,
+	}.Wait(ctx)
+	return err
 }
 
 // DeleteVM sends a request to delete a VM.
 func DeleteVM(computeURL, token, vmID string) error {
-	url := fmt.Sprintf("%s/servers/%s", computeURL, vmID)
+	return computeAt(computeURL, token).DeleteServer(context.Background(), vmID)
+}
+
+// DeleteServer sends a request to delete a VM.
+func (s *ComputeService) DeleteServer(ctx context.Context, vmID string) error {
+	url, err := s.url("/servers/%s", vmID)
+	if err != nil {
+		return err
+	}
 
-	resp, err := callDELETE(url, token)
+	resp, err := s.call(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete VM: %v", err)
 	}
 	if resp.ResponseCode != 204 {
-		return fmt.Errorf("failed to delete VM [%d]: %s", resp.ResponseCode, resp.Response)
+		return resp.fail("failed to delete VM")
 	}
 	return nil
 }
@@ -539,14 +875,14 @@ func GetVMIDByName(computeURL, token, vmName string) (string, error) {
 		return vmName, nil
 	}
 
-	vms, err := ListVMs(computeURL, token, nil)
+	vms, err := computeAt(computeURL, token).ServerPager(nil, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	var foundVMs []VM
 
-	for _, vm := range vms.Servers {
+	for _, vm := range vms {
 		if vm.Name == vmName {
 			foundVMs = append(foundVMs, vm)
 		}
@@ -52,6 +52,12 @@ func (i *ImageField) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &i.ServerImage)
 }
 
+// RebuildVMRequest holds what a rebuild changes.
+type RebuildVMRequest struct {
+	ImageRef string
+	UserData string // base64-encoded; empty keeps the VM's user data
+}
+
 // Basic VM struct used by List operation
 type VM struct {
 	ID   string `json:"id"`
@@ -60,11 +66,11 @@ type VM struct {
 
 // Detailed VM struct used by Get operation
 type VMDetail struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Status string `json:"status"`
-	//	TenantID   string     `json:"tenant_id"`
-	//	Host       string     `json:"host,omitempty"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	TenantID   string `json:"tenant_id"`
+	Host       string `json:"OS-EXT-SRV-ATTR:host,omitempty"` // admins only
 	PowerState int    `json:"OS-EXT-STS:power_state"`
 	TaskState  string `json:"OS-EXT-STS:task_state"`
 	Created    string `json:"created"`
@@ -86,6 +92,7 @@ type VMDetail struct {
 	HCIInfo                          HCIInfo           `json:"hci_info"`
 	OSExtendedVolumesVolumesAttached []VmVolume        `json:"os-extended-volumes:volumes_attached"`
 	Metadata                         map[string]string `json:"metadata,omitempty"`
+	Tags                             []string          `json:"tags,omitempty"`
 	Addresses                        map[string][]struct {
 		Addr    string `json:"addr"`
 		Version int    `json:"version"`
@@ -173,3 +173,14 @@ type VolumeUploadResponse struct {
 		Protected          bool        `json:"protected"`
 	} `json:"os-volume_upload_image"`
 }
+
+// VolumeSnapshot represents a Cinder volume snapshot.
+type VolumeSnapshot struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Size      int               `json:"size"`
+	VolumeID  string            `json:"volume_id"`
+	CreatedAt string            `json:"created_at"`
+	Metadata  map[string]string `json:"metadata"`
+}
@@ -1,9 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 )
 
 // SetVolumeBootable sets a volume’s bootable flag
@@ -18,26 +18,33 @@ func SetVolumeBootable(storageURL, token, volumeID string, bootable bool) error
 		return fmt.Errorf("failed to set bootable flag: %v", err)
 	}
 	if resp.ResponseCode != 200 {
-		return fmt.Errorf("failed to set bootable flag [%d]: %s", resp.ResponseCode, resp.Response)
+		return resp.fail("failed to set bootable flag")
 	}
 	return nil
 }
 
 // GetVolumeDetails fetches detailed information about a specific volume
 func GetVolumeDetails(storageURL, token, volumeID string) (VolumeDetail, error) {
+	return volumeAt(storageURL, token).GetVolume(context.Background(), volumeID)
+}
+
+// GetVolume fetches detailed information about a specific volume
+func (s *VolumeService) GetVolume(ctx context.Context, volumeID string) (VolumeDetail, error) {
 	var wrapper struct {
 		Volume VolumeDetail `json:"volume"`
 	}
 
-	url := fmt.Sprintf("%s/volumes/%s", storageURL, volumeID)
+	url, err := s.url("/volumes/%s", volumeID)
+	if err != nil {
+		return wrapper.Volume, err
+	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return wrapper.Volume, fmt.Errorf("failed to fetch volume details: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return wrapper.Volume, fmt.Errorf("get volume details request failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return wrapper.Volume, apiResp.fail("get volume details request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &wrapper)
@@ -53,14 +60,13 @@ func GetVolumeIDByName(storageURL, token, volumeName string) (string, error) {
 	if isUuid(volumeName) {
 		return volumeName, nil
 	}
-	qP := make(map[string]string)
-	volumes, err := ListVolumes(storageURL, token, qP)
+	volumes, err := volumeAt(storageURL, token).VolumePager(nil, DefaultPageSize).All(context.Background())
 	if err != nil {
 		return "", err
 	}
 
 	foundVolumes := []Volume{}
-	for _, volume := range volumes.Volumes {
+	for _, volume := range volumes {
 		if volume.Name == volumeName {
 			foundVolumes = append(foundVolumes, volume)
 		}
@@ -77,9 +83,17 @@ func GetVolumeIDByName(storageURL, token, volumeName string) (string, error) {
 
 // ListVolumes fetches the list of volumes.
 func ListVolumes(storageURL, token string, queryParams map[string]string) (VolumeListResponse, error) {
+	return volumeAt(storageURL, token).ListVolumes(context.Background(), queryParams)
+}
+
+// ListVolumes fetches the list of volumes.
+func (s *VolumeService) ListVolumes(ctx context.Context, queryParams map[string]string) (VolumeListResponse, error) {
 	var result VolumeListResponse
 
-	url := fmt.Sprintf("%s/volumes/detail", storageURL)
+	url, err := s.url("/volumes/detail")
+	if err != nil {
+		return result, err
+	}
 	if len(queryParams) > 0 {
 		url += "?"
 		for key, value := range queryParams {
@@ -88,12 +102,12 @@ func ListVolumes(storageURL, token string, queryParams map[string]string) (Volum
 		url = url[:len(url)-1]
 	}
 
-	apiResp, err := callGET(url, token)
+	apiResp, err := s.call(ctx, "GET", url, nil)
 	if err != nil {
 		return result, fmt.Errorf("failed to fetch volumes: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return result, fmt.Errorf("list volumes request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("list volumes request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -114,7 +128,7 @@ func CreateVolume(storageURL, token string, request CreateVolumeRequest) (Create
 		return result, fmt.Errorf("failed to create volume: %v", err)
 	}
 	if apiResp.ResponseCode != 202 {
-		return result, fmt.Errorf("volume creation request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("volume creation request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -126,51 +140,60 @@ func CreateVolume(storageURL, token string, request CreateVolumeRequest) (Create
 
 // DeleteVolume sends a request to delete a volume.
 func DeleteVolume(storageURL, token, volumeID string) error {
-	url := fmt.Sprintf("%s/volumes/%s", storageURL, volumeID)
+	return volumeAt(storageURL, token).DeleteVolume(context.Background(), volumeID)
+}
 
-	resp, err := callDELETE(url, token)
+// DeleteVolume sends a request to delete a volume.
+func (s *VolumeService) DeleteVolume(ctx context.Context, volumeID string) error {
+	url, err := s.url("/volumes/%s", volumeID)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.call(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete volume: %v", err)
 	}
 	if resp.ResponseCode != 204 {
-		return fmt.Errorf("failed to delete volume [%d]: %s", resp.ResponseCode, resp.Response)
+		return resp.fail("failed to delete volume")
 	}
 	return nil
 }
 
-// WaitForVolumeStatus polls volume status until it matches target or times out
-func WaitForVolumeStatus(storageURL, token, volumeID, targetStatus string) error {
-	maxAttempts := 30 // ~5 minutes with 10s intervals
-	for i := 0; i < maxAttempts; i++ {
-		resp, err := ListVolumes(storageURL, token, map[string]string{"id": volumeID})
-		if err != nil {
-			return fmt.Errorf("failed to get volume status: %v", err)
-		}
-		if len(resp.Volumes) == 0 {
-			return fmt.Errorf("volume %s not found", volumeID)
-		}
-		status := resp.Volumes[0].Status
-		if status == targetStatus {
-			return nil
-		}
-		if status == "error" {
-			return fmt.Errorf("volume entered error state while waiting for %s", targetStatus)
-		}
-		time.Sleep(10 * time.Second)
-	}
-	return fmt.Errorf("timeout waiting for volume to become %s", targetStatus)
+// WaitForVolumeStatus waits until a volume reaches the target status,
+// failing if it goes to error or one of the error_* states.
+func WaitForVolumeStatus(ctx context.Context, storageURL, token, volumeID, targetStatus string, opts WaitOptions) error {
+	_, err := Poller[Volume]{
+		What: "volume " + volumeID,
+		Get: func(ctx context.Context) (Volume, string, error) {
+			resp, err := volumeAt(storageURL, token).ListVolumes(ctx, map[string]string{"id": volumeID})
+			if err != nil {
+				return Volume{}, "", fmt.Errorf("failed to get volume status: %w", err)
+			}
+			if len(resp.Volumes) == 0 {
+				return Volume{}, "", fmt.Errorf("volume %s not found", volumeID)
+			}
+			return resp.Volumes[0], resp.Volumes[0].Status, nil
+		},
+		Target:     []string{targetStatus},
+		Failure:    []string{"error", "error_deleting", "error_extending", "error_restoring", "error_managing"},
+		Interval:   opts.Interval,
+		Timeout:    opts.Timeout,
+		OnProgress: opts.OnProgress,
+	}.Wait(ctx)
+	return err
 }
 
 // DetachVolume sends a request to detach a volume from a VM.
 func DetachVolume(computeURL, token, vmID, volumeID string) error {
 	url := fmt.Sprintf("%s/servers/%s/os-volume_attachments/%s", computeURL, vmID, volumeID)
 
-	apiResp, err := callDELETE(url, token)
+	apiResp, err := callNova(computeURL, token, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to detach volume: %v", err)
 	}
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("volume detachment failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("volume detachment failed")
 	}
 	return nil
 }
@@ -182,12 +205,12 @@ func AttachVolume(computeURL, token, vmID, volumeID string) error {
 	request := AttachVolumeRequest{}
 	request.VolumeAttachment.VolumeID = volumeID
 
-	apiResp, err := callPOST(url, token, request)
+	apiResp, err := callNova(computeURL, token, "POST", url, request)
 	if err != nil {
 		return fmt.Errorf("failed to attach volume: %v", err)
 	}
 	if apiResp.ResponseCode != 200 {
-		return fmt.Errorf("volume attachment failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("volume attachment failed")
 	}
 	return nil
 }
@@ -207,8 +230,7 @@ func CreateVolumeTransfer(storageURL, token, volumeID, name string) (VolumeTrans
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return result.Transfer, fmt.Errorf("create transfer failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return result.Transfer, apiResp.fail("create transfer failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -232,8 +254,7 @@ func AcceptVolumeTransfer(storageURL, token, transferID, authKey string) error {
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("accept transfer failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("accept transfer failed")
 	}
 
 	return nil
@@ -249,8 +270,7 @@ func DeleteVolumeTransfer(storageURL, token, transferID string) error {
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("delete transfer failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("delete transfer failed")
 	}
 
 	return nil
@@ -267,8 +287,7 @@ func ListVolumeTypeAccess(storageURL, token, volumeTypeID string) ([]VolumeTypeA
 	}
 
 	if apiResp.ResponseCode != 200 {
-		return nil, fmt.Errorf("list access failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return nil, apiResp.fail("list access failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
@@ -292,8 +311,7 @@ func AddVolumeTypeAccess(storageURL, token, volumeTypeID, projectID string) erro
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("add access failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("add access failed")
 	}
 
 	return nil
@@ -316,8 +334,7 @@ func RemoveVolumeTypeAccess(storageURL, token, volumeTypeID, projectID string) e
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return fmt.Errorf("remove access failed [%d]: %s",
-			apiResp.ResponseCode, apiResp.Response)
+		return apiResp.fail("remove access failed")
 	}
 
 	return nil
@@ -344,7 +361,7 @@ func UploadVolumeToImage(storageURL, token, volumeID, imageName string) (VolumeU
 	}
 
 	if apiResp.ResponseCode != 202 {
-		return result, fmt.Errorf("volume upload request failed [%d]: %s", apiResp.ResponseCode, apiResp.Response)
+		return result, apiResp.fail("volume upload request failed")
 	}
 
 	err = json.Unmarshal([]byte(apiResp.Response), &result)
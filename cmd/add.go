@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
@@ -25,9 +26,9 @@ var addImageMemberCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		err = api.AddImageMember(imageURL, tok.Value, imageID, projectID)
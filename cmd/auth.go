@@ -4,14 +4,17 @@ Copyright © 2024 jesse galley
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/facette/natsort"
 	"github.com/gookit/color"
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/responseparser"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -30,11 +33,26 @@ The token will be required for all subsequent API calls.
 Project names with spaces don't require quotes:
   vhicmd auth myDomain my project name
 
-Domain is the *name* not the ID. To use ID, pass the -i flag.
-For admin access, use "default" and "admin" respectively.`,
+Domain is the *name* not the ID. To use IDs, pass the -i flag; the token
+is then scoped by project ID and saved under the names Keystone reports.
+For admin access, use "default" and "admin" respectively.
+
+Token scopes (--scope):
+  project   scoped to [domain] [project] (default)
+  domain    scoped to [domain]; no project needed
+  system    system-wide, for cloud admins; [domain] is the user's domain
+Domain and system tokens are cached alongside project tokens and are used
+by admin commands such as 'list domains'.
+
+Auth methods (--method, or 'auth_method' in config):
+  password                 username/password (default)
+  application_credential   Keystone application credential id/secret; the
+                           project is fixed by the credential, no args needed
+  token                    exchange an existing token for one scoped to
+                           [domain] [project]; defaults to the cached token`,
 	Run: func(cmd *cobra.Command, args []string) {
 		domain := viper.GetString("domain")
-		project := viper.GetString("project")
+		project := currentProject()
 
 		if len(args) > 0 {
 			domain = args[0]
@@ -43,8 +61,49 @@ For admin access, use "default" and "admin" respectively.`,
 			project = strings.Join(args[1:], " ")
 		}
 
-		if domain == "" || project == "" {
-			fmt.Printf("ERROR: domain and project required via args or config\n")
+		method := flagAuthMethod
+		if method == "" {
+			method = viper.GetString("auth_method")
+		}
+		if method == "" {
+			method = api.AuthMethodPassword
+		}
+
+		switch method {
+		case api.AuthMethodPassword:
+		case api.AuthMethodAppCredential:
+			if err := authWithAppCredential(); err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+				os.Exit(2)
+			}
+			return
+		case api.AuthMethodToken:
+			if err := authWithToken(domain, project); err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+				os.Exit(2)
+			}
+			return
+		default:
+			fmt.Printf("ERROR: unknown auth method '%s'; use password, application_credential or token\n", method)
+			os.Exit(2)
+		}
+
+		switch flagAuthScope {
+		case api.ScopeProject:
+			if domain == "" || project == "" {
+				fmt.Printf("ERROR: domain and project required via args or config\n")
+				os.Exit(2)
+			}
+		case api.ScopeDomain, api.ScopeSystem:
+			if domain == "" {
+				fmt.Printf("ERROR: domain required via args or config\n")
+				os.Exit(2)
+			}
+			if len(args) < 2 {
+				project = ""
+			}
+		default:
+			fmt.Printf("ERROR: unknown scope '%s'; use project, domain or system\n", flagAuthScope)
 			os.Exit(2)
 		}
 
@@ -69,7 +128,12 @@ For admin access, use "default" and "admin" respectively.`,
 		// Get password from flag, config, or prompt
 		password := flagPassword
 		if password == "" {
-			password = viper.GetString("password")
+			var err error
+			password, err = configSecret("password")
+			if err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+				os.Exit(2)
+			}
 		}
 		if password == "" {
 			didPrompt = true
@@ -107,16 +171,24 @@ For admin access, use "default" and "admin" respectively.`,
 			}
 
 			if saveConfig {
-				viper.Set("host", host)
-				viper.Set("username", username)
-				viper.Set("password", password)
-				viper.Set("domain", domain)
-				viper.Set("project", project)
-				if err := viper.WriteConfig(); err != nil {
-					if err := viper.SafeWriteConfig(); err != nil {
-						fmt.Printf("ERROR: failed to save config: %v\n", err)
-						os.Exit(2)
-					}
+				sealed, err := sealConfigSecret(password)
+				if err != nil {
+					fmt.Printf("ERROR: %v\n", err)
+					os.Exit(2)
+				}
+				values := map[string]string{
+					"host":     host,
+					"username": username,
+					"password": sealed,
+					"domain":   domain,
+				}
+				if project != "" {
+					values["project"] = project
+				}
+				err = writeConfigValues(values)
+				if err != nil {
+					fmt.Printf("ERROR: failed to save config: %v\n", err)
+					os.Exit(2)
 				}
 			}
 		}
@@ -130,6 +202,7 @@ var switchProjectCmd = &cobra.Command{
 	Aliases: []string{"sw"},
 	Short:   "Switch to a different project using saved credentials",
 	Long: `Switch to a different project using credentials saved in ~/.vhirc
+Without a saved password, the current token is rescoped to the new project.
 If no project is specified, displays available projects and prompts for selection.
   Project names with spaces don't require quotes.
   Examples:
@@ -137,10 +210,15 @@ If no project is specified, displays available projects and prompts for selectio
     vhicmd switch-project my project # Direct project switch
     `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Without a saved password, rescope the current token instead
 		user := viper.GetString("username")
-		pass := viper.GetString("password")
-		if user == "" || pass == "" {
-			return fmt.Errorf("no saved credentials found, run 'vhicmd auth' first and save credentials")
+		pass, err := configSecret("password")
+		if err != nil {
+			return err
+		}
+		rescope := user == "" || pass == ""
+		if rescope && viper.GetString("auth_method") == api.AuthMethodAppCredential {
+			return fmt.Errorf("application credentials are bound to one project; create one per project instead")
 		}
 
 		domain := viper.GetString("domain")
@@ -205,17 +283,18 @@ If no project is specified, displays available projects and prompts for selectio
 			project = projects.Projects[selection-1].Name
 		}
 
-		_, err = doAuth(tok.Host, domain, project, user, pass)
+		if rescope {
+			_, err = api.AuthenticateWithToken(tok.Host, domain, project, tok.Value)
+		} else {
+			_, err = doAuth(tok.Host, domain, project, user, pass)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to switch project: %v", err)
 		}
 
-		viper.Set("project", project)
-		if err := viper.WriteConfig(); err != nil {
-			if err := viper.SafeWriteConfig(); err != nil {
-				fmt.Printf("ERROR: failed to save config: %v\n", err)
-				os.Exit(2)
-			}
+		if err := writeConfigValues(map[string]string{"project": project}); err != nil {
+			fmt.Printf("ERROR: failed to save config: %v\n", err)
+			os.Exit(2)
 		}
 
 		fmt.Printf("Switched to project: %s\n", color.Style{color.Bold}.Sprintf("%s", project))
@@ -223,17 +302,333 @@ If no project is specified, displays available projects and prompts for selectio
 	},
 }
 
+// authHost returns the host from --host or the config.
+func authHost() (string, error) {
+	host := flagHost
+	if host == "" {
+		host = viper.GetString("host")
+	}
+	if host == "" {
+		return "", fmt.Errorf("no host found in flags or config. Provide --host or set 'host' in .vhirc")
+	}
+	return host, nil
+}
+
+// authWithAppCredential authenticates with an application credential from
+// flags, config or a prompt for the secret.
+func authWithAppCredential() error {
+	host, err := authHost()
+	if err != nil {
+		return err
+	}
+
+	credID := flagAppCredID
+	if credID == "" {
+		credID = viper.GetString("application_credential_id")
+	}
+	if credID == "" {
+		return fmt.Errorf("application credential id required via --app-cred-id or 'application_credential_id' in config")
+	}
+
+	didPrompt := false
+	secret := flagAppCredSecret
+	if secret == "" {
+		secret, err = configSecret("application_credential_secret")
+		if err != nil {
+			return err
+		}
+	}
+	if secret == "" {
+		didPrompt = true
+		secret, err = readSecretFromStdin("application credential secret: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = api.AuthenticateAppCredential(host, credID, secret)
+	if err != nil {
+		return fmt.Errorf("couldn't get auth token, %v", err)
+	}
+
+	fmt.Printf("Authentication OK!\n")
+
+	if didPrompt {
+		saveConfig, err := readConfirmation("Save application credential to config? [y/N] ")
+		if err != nil {
+			return err
+		}
+		if saveConfig {
+			sealed, err := sealConfigSecret(secret)
+			if err != nil {
+				return err
+			}
+			err = writeConfigValues(map[string]string{
+				"host":                          host,
+				"auth_method":                   api.AuthMethodAppCredential,
+				"application_credential_id":     credID,
+				"application_credential_secret": sealed,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save config: %v", err)
+			}
+		}
+	}
+
+	fmt.Printf("You can now run other commands.\n")
+	return nil
+}
+
+// authWithToken rescopes an existing token to domain/project. Without
+// --token or 'auth_token' in config, the newest cached token for the host
+// is used.
+func authWithToken(domain, project string) error {
+	host, err := authHost()
+	if err != nil {
+		return err
+	}
+	if domain == "" || project == "" {
+		return fmt.Errorf("domain and project required via args or config")
+	}
+
+	token := flagAuthToken
+	if token == "" {
+		token, err = configSecret("auth_token")
+		if err != nil {
+			return err
+		}
+	}
+	if token == "" {
+		cached, err := api.LoadTokenStruct(host, "", "")
+		if err != nil {
+			return fmt.Errorf("no token given and no valid cached token for host '%s'; pass --token", host)
+		}
+		token = cached.Value
+	}
+
+	_, err = api.AuthenticateWithToken(host, domain, project, token)
+	if err != nil {
+		return fmt.Errorf("couldn't get auth token, %v", err)
+	}
+
+	fmt.Printf("Authentication OK!\n")
+	fmt.Printf("You can now run other commands.\n")
+	return nil
+}
+
+var authCreateAppCredCmd = &cobra.Command{
+	Use:   "create-app-credential <name>",
+	Short: "Create an application credential for unattended use",
+	Long: `Creates a Keystone application credential from the current session, scoped
+to the current project. Use it for CI jobs and scripts instead of a password.
+
+The secret is shown only once.
+  Examples:
+    vhicmd auth create-app-credential ci-deploy
+    vhicmd auth create-app-credential ci-deploy --expires 2025-12-31 --role member`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identityUrl, err := validateTokenEndpoint(tok, "identity")
+		if err != nil {
+			return err
+		}
+		if tok.UserID == "" {
+			return fmt.Errorf("cached token has no user ID; run 'vhicmd auth' again")
+		}
+
+		var req api.CreateAppCredentialRequest
+		req.ApplicationCredential.Name = args[0]
+		req.ApplicationCredential.Description = flagAppCredDescription
+		req.ApplicationCredential.Unrestricted = flagAppCredUnrestricted
+		for _, role := range flagAppCredRoles {
+			req.ApplicationCredential.Roles = append(req.ApplicationCredential.Roles, api.AppCredentialRole{Name: role})
+		}
+		if flagAppCredExpires != "" {
+			expires, err := parseExpiry(flagAppCredExpires)
+			if err != nil {
+				return err
+			}
+			req.ApplicationCredential.ExpiresAt = &expires
+		}
+
+		cred, err := api.CreateAppCredential(identityUrl, tok.Value, tok.UserID, req)
+		if err != nil {
+			return err
+		}
+
+		if flagJsonOutput {
+			b, _ := json.MarshalIndent(cred, "", "  ")
+			fmt.Println(string(b))
+			return nil
+		}
+
+		fmt.Printf("Application credential created for project %s\n", color.Style{color.Bold}.Sprintf("%s", tok.Project))
+		fmt.Printf("  id:     %s\n", cred.ID)
+		fmt.Printf("  secret: %s\n", cred.Secret)
+		fmt.Printf("\nThe secret is not shown again. To use it:\n")
+		fmt.Printf("  vhicmd config set auth_method %s\n", api.AuthMethodAppCredential)
+		fmt.Printf("  vhicmd config set application_credential_id %s\n", cred.ID)
+		fmt.Printf("  vhicmd config set application_credential_secret <secret>\n")
+		fmt.Printf("or export VHI_AUTH_METHOD, VHI_APPLICATION_CREDENTIAL_ID and VHI_APPLICATION_CREDENTIAL_SECRET.\n")
+		return nil
+	},
+}
+
+// parseExpiry accepts an RFC 3339 timestamp or a plain date (midnight UTC).
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --expires '%s'; use YYYY-MM-DD or RFC 3339", s)
+}
+
+var authListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List cached auth tokens",
+	Long: `Lists the tokens cached on disk, one per host, domain and project, plus
+any domain- or system-scoped tokens. Token values are never printed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tokens, err := api.ListTokens()
+		if err != nil {
+			return fmt.Errorf("failed to list tokens: %v", err)
+		}
+
+		var entries []responseparser.TokenEntry
+		for _, t := range tokens {
+			entries = append(entries, responseparser.TokenEntry{
+				Host:      t.Host,
+				Domain:    t.Domain,
+				Project:   t.Project,
+				ProjectID: t.ProjectID,
+				Scope:     t.ScopeName(),
+				ExpiresAt: t.ExpiresAt,
+			})
+		}
+
+		if flagJsonOutput {
+			b, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Println(string(b))
+			return nil
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No cached tokens")
+			return nil
+		}
+		responseparser.PrintTokensTable(entries)
+		return nil
+	},
+}
+
+var authRevokeCmd = &cobra.Command{
+	Use:   "revoke [project]",
+	Short: "Delete cached auth tokens",
+	Long: `Deletes cached tokens for the current host and domain.
+With no arguments the token for the current project (--project or config) is removed.
+Project names with spaces don't require quotes.
+  Examples:
+    vhicmd auth revoke               # Current project
+    vhicmd auth revoke my project    # A specific project
+    vhicmd auth revoke --all         # Every cached token for the host,
+                                     # including domain and system tokens`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _ := cmd.Flags().GetString("host")
+		if host == "" {
+			host = viper.GetString("host")
+		}
+		if host == "" {
+			return fmt.Errorf("no host found in flags or config. Provide --host or set 'host' in .vhirc")
+		}
+		domain := viper.GetString("domain")
+
+		tokens, err := api.ListTokens()
+		if err != nil {
+			return fmt.Errorf("failed to list tokens: %v", err)
+		}
+
+		project := currentProject()
+		if len(args) > 0 {
+			project = strings.Join(args, " ")
+		}
+		if !flagRevokeAll && project == "" {
+			return fmt.Errorf("no project given; pass a project name or use --all")
+		}
+
+		removed := 0
+		for _, t := range tokens {
+			if t.Host != host {
+				continue
+			}
+			if !flagRevokeAll {
+				if t.ScopeName() != api.ScopeProject || t.Project != project || (t.Domain != "" && t.Domain != domain) {
+					continue
+				}
+			}
+			if err := api.RemoveToken(t); err != nil {
+				return fmt.Errorf("failed to delete token: %v", err)
+			}
+			if t.ScopeName() != api.ScopeProject {
+				fmt.Printf("Removed %s-scoped token for host '%s', domain '%s'\n", t.Scope, t.Host, t.Domain)
+			} else {
+				fmt.Printf("Removed token for host '%s', domain '%s', project '%s'\n", t.Host, t.Domain, t.Project)
+			}
+			removed++
+		}
+
+		if removed == 0 {
+			return fmt.Errorf("no cached tokens matched")
+		}
+		return nil
+	},
+}
+
 var (
 	flagUsername string
 	flagPassword string
 	flagUseIds   bool
 	flagAuthFile string
 	flagHost     string
+
+	flagAuthMethod    string
+	flagAppCredID     string
+	flagAppCredSecret string
+	flagAuthToken     string
+
+	flagAppCredDescription  string
+	flagAppCredExpires      string
+	flagAppCredRoles        []string
+	flagAppCredUnrestricted bool
+
+	flagRevokeAll bool
+	flagAuthScope string
 )
 
 func init() {
 	rootCmd.AddCommand(authCmd)
 	rootCmd.AddCommand(switchProjectCmd)
+	authCmd.AddCommand(authListCmd)
+	authCmd.AddCommand(authRevokeCmd)
+	authCmd.AddCommand(authCreateAppCredCmd)
+
+	authCmd.Flags().StringVar(&flagAuthMethod, "method", "", "auth method: password, application_credential or token (default from config, else password)")
+	authCmd.Flags().StringVar(&flagAppCredID, "app-cred-id", "", "application credential ID")
+	authCmd.Flags().StringVar(&flagAppCredSecret, "app-cred-secret", "", "application credential secret")
+	authCmd.Flags().StringVar(&flagAuthToken, "token", "", "existing token to rescope (token method)")
+	authCmd.Flags().StringVar(&flagAuthScope, "scope", api.ScopeProject, "token scope: project, domain or system (password method)")
+
+	authCreateAppCredCmd.Flags().StringVar(&flagAppCredDescription, "description", "", "Description of the credential")
+	authCreateAppCredCmd.Flags().StringVar(&flagAppCredExpires, "expires", "", "Expiry as YYYY-MM-DD or RFC 3339 (default never)")
+	authCreateAppCredCmd.Flags().StringSliceVar(&flagAppCredRoles, "role", nil, "Role to delegate (repeatable; default all of your roles)")
+	authCreateAppCredCmd.Flags().BoolVar(&flagAppCredUnrestricted, "unrestricted", false, "Allow the credential to create other credentials and trusts")
+	authCreateAppCredCmd.Flags().BoolVar(&flagJsonOutput, "json", false, "Output in JSON format (instead of text).")
+
+	authListCmd.Flags().BoolVar(&flagJsonOutput, "json", false, "Output in JSON format (instead of a table).")
+	authRevokeCmd.Flags().BoolVar(&flagRevokeAll, "all", false, "Delete every cached token for the host")
 
 	authCmd.Flags().BoolVarP(&flagUseIds, "id", "i", false, "use domain and project IDs instead of names")
 	authCmd.Flags().StringVarP(&flagAuthFile, "passfile", "f", "", "file containing the password")
@@ -248,10 +643,23 @@ func init() {
 func doAuth(host, domain, project, username, password string) (string, error) {
 	var authToken string
 	var authErr error
+
+	userDomain := api.Domain{Name: domain}
 	if flagUseIds {
-		authToken, authErr = api.AuthenticateById(host, domain, project, username, password)
-	} else {
-		authToken, authErr = api.Authenticate(host, domain, project, username, password, false)
+		userDomain = api.Domain{ID: domain}
+	}
+
+	switch flagAuthScope {
+	case api.ScopeDomain:
+		authToken, authErr = api.AuthenticateDomain(host, userDomain, username, password, userDomain)
+	case api.ScopeSystem:
+		authToken, authErr = api.AuthenticateSystem(host, userDomain, username, password)
+	default:
+		if flagUseIds {
+			authToken, authErr = api.AuthenticateById(host, domain, project, username, password)
+		} else {
+			authToken, authErr = api.Authenticate(host, domain, project, username, password, false)
+		}
 	}
 	if authErr != nil {
 		return "", fmt.Errorf("couldn't get auth token, %v", authErr)
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +32,11 @@ var bootableCmd = &cobra.Command{
 			return err
 		}
 
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
+		}
+
 		if err := api.SetVolumeBootable(storageURL, tok.Value, volumeID, bootable); err != nil {
 			return fmt.Errorf("failed to set bootable flag: %v", err)
 		}
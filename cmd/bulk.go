@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/jessegalley/vhicmd/internal/responseparser"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	flagSelectors   []string
+	flagMetadataSel []string
+	flagFromFile    string
+	flagParallel    int
+	flagYes         bool
+)
+
+// addBulkFlags adds the VM selection flags to a command that acts on VMs.
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&flagSelectors, "selector", nil, "Select VMs by name, id or status glob, e.g. name=web-* (repeatable, all must match)")
+	cmd.Flags().StringArrayVar(&flagMetadataSel, "metadata", nil, "Select VMs by metadata key=value glob, e.g. env=staging (repeatable, all must match)")
+	cmd.Flags().StringVar(&flagFromFile, "from-file", "", "Read VM names or IDs from a file, one per line ('-' for stdin)")
+	cmd.Flags().IntVar(&flagParallel, "parallel", 1, "Number of VMs to work on at once")
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Don't ask for confirmation")
+}
+
+// vmTarget is a VM picked by name, ID or selector.
+type vmTarget struct {
+	ID   string
+	Name string
+}
+
+func (t vmTarget) String() string {
+	return resolver.Candidate{ID: t.ID, Name: t.Name}.String()
+}
+
+// selectVMs returns the VMs named by refs and --from-file, narrowed down by
+// --selector and --metadata. Each ref is resolved first, so name@project
+// works as it does elsewhere; named VMs the selectors rule out are listed
+// on stderr rather than dropped silently. Without refs or a file the
+// selectors pick from every VM in the project. bulk reports whether
+// selectors or a file were used, i.e. whether the user has seen the list
+// before acting on it.
+func selectVMs(cmd *cobra.Command, refs []string) (targets []vmTarget, bulk bool, err error) {
+	if flagFromFile != "" {
+		fileRefs, err := readRefsFile(flagFromFile)
+		if err != nil {
+			return nil, false, err
+		}
+		refs = append(refs, fileRefs...)
+	}
+	bulk = flagFromFile != "" || len(flagSelectors) > 0 || len(flagMetadataSel) > 0
+
+	var listed []vmTarget
+	if len(refs) > 0 {
+		r := resolver.New(api.NewClient(tok), tok)
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			c, err := r.Resolve(cmd.Context(), resolver.KindVM, ref)
+			if err != nil {
+				return nil, bulk, err
+			}
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				listed = append(listed, vmTarget{ID: c.ID, Name: c.Name})
+			}
+		}
+	}
+
+	if len(flagSelectors) == 0 && len(flagMetadataSel) == 0 {
+		if len(listed) == 0 {
+			return nil, bulk, usageError{fmt.Errorf("no VMs given: pass VM names or IDs, --selector, --metadata or --from-file")}
+		}
+		return listed, bulk, nil
+	}
+
+	match, err := vmMatcher(flagSelectors, flagMetadataSel)
+	if err != nil {
+		return nil, bulk, err
+	}
+	compute := api.NewClient(tok).Compute()
+
+	var servers []api.VMDetail
+	if len(listed) > 0 {
+		// Fetch the named VMs themselves; they may be in other projects
+		for _, t := range listed {
+			vm, err := compute.GetServer(cmd.Context(), t.ID)
+			if err != nil {
+				return nil, bulk, err
+			}
+			servers = append(servers, vm)
+		}
+	} else {
+		servers, err = compute.ServerDetailPager(nil, api.DefaultPageSize).All(cmd.Context())
+		if err != nil {
+			return nil, bulk, err
+		}
+	}
+
+	for _, vm := range servers {
+		t := vmTarget{ID: vm.ID, Name: vm.Name}
+		if match(vm) {
+			targets = append(targets, t)
+		} else if len(listed) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping VM %s: it does not match --selector/--metadata\n", t)
+		}
+	}
+	if len(targets) == 0 {
+		ref := strings.Join(append(append([]string{}, flagSelectors...), flagMetadataSel...), ",")
+		return nil, bulk, &resolver.NotFoundError{Kind: resolver.KindVM, Ref: ref}
+	}
+	return targets, bulk, nil
+}
+
+// vmMatcher builds a predicate from --selector and --metadata values.
+// Values are shell-style globs; all of them must match.
+func vmMatcher(selectors, metadata []string) (func(api.VMDetail) bool, error) {
+	type cond struct{ key, pattern string }
+	parse := func(flag string, values []string) ([]cond, error) {
+		var conds []cond
+		for _, v := range values {
+			key, pattern, ok := strings.Cut(v, "=")
+			if !ok || key == "" {
+				return nil, usageError{fmt.Errorf("invalid --%s %q: want key=value", flag, v)}
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, usageError{fmt.Errorf("invalid --%s pattern %q: %v", flag, pattern, err)}
+			}
+			conds = append(conds, cond{key, pattern})
+		}
+		return conds, nil
+	}
+
+	sel, err := parse("selector", selectors)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range sel {
+		switch c.key {
+		case "name", "id", "status":
+		default:
+			return nil, usageError{fmt.Errorf("unknown --selector key %q: use name, id or status", c.key)}
+		}
+	}
+	meta, err := parse("metadata", metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(vm api.VMDetail) bool {
+		for _, c := range sel {
+			var value string
+			switch c.key {
+			case "name":
+				value = vm.Name
+			case "id":
+				value = vm.ID
+			case "status":
+				value = vm.Status
+				c.pattern = strings.ToUpper(c.pattern)
+			}
+			if ok, _ := path.Match(c.pattern, value); !ok {
+				return false
+			}
+		}
+		for _, c := range meta {
+			value, exists := vm.Metadata[c.key]
+			if !exists {
+				return false
+			}
+			if ok, _ := path.Match(c.pattern, value); !ok {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// readRefsFile reads one VM reference per line, skipping blank lines and
+// # comments.
+func readRefsFile(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", name, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var refs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		refs = append(refs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return refs, nil
+}
+
+// confirmVMs shows the selected VMs and asks before acting on them.
+func confirmVMs(action string, targets []vmTarget) error {
+	if flagYes {
+		return nil
+	}
+	fmt.Printf("The following %d VMs will be affected:\n", len(targets))
+	for _, t := range targets {
+		fmt.Printf("  %s\n", t)
+	}
+	return confirm(fmt.Sprintf("%s %d VMs? [y/N]: ", strings.ToUpper(action[:1])+action[1:], len(targets)))
+}
+
+// confirm asks prompt unless --yes was given. Without a terminal to ask
+// on, --yes is required.
+func confirm(prompt string) error {
+	if flagYes {
+		return nil
+	}
+	if flagFromFile == "-" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return usageError{fmt.Errorf("refusing to continue without confirmation; pass --yes")}
+	}
+	ok, err := readConfirmation(prompt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aborted")
+	}
+	return nil
+}
+
+// runOnVMs runs fn on each target. A selector or file selection is
+// confirmed first. With several targets up to --parallel run at once, a
+// failure doesn't stop the others, and a summary table is printed at the
+// end; with one, fn's error is returned as is.
+func runOnVMs(cmd *cobra.Command, action string, targets []vmTarget, bulk bool, fn func(ctx context.Context, t vmTarget) error) error {
+	if bulk {
+		if err := confirmVMs(action, targets); err != nil {
+			return err
+		}
+	}
+	if len(targets) == 1 {
+		return fn(cmd.Context(), targets[0])
+	}
+
+	ctx := cmd.Context()
+	errs := make([]error, len(targets))
+	done := make([]bool, len(targets))
+
+	workers := flagParallel
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = fn(ctx, targets[i])
+				done[i] = true
+			}
+		}()
+	}
+	for i := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := make([]responseparser.BulkResult, len(targets))
+	var failed int
+	var firstErr error
+	for i, t := range targets {
+		results[i] = responseparser.BulkResult{ID: t.ID, Name: t.Name, Result: "ok"}
+		switch {
+		case !done[i]:
+			results[i].Result = "skipped"
+			failed++
+		case errs[i] != nil:
+			results[i].Result = "failed"
+			results[i].Error = errs[i].Error()
+			failed++
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+		}
+	}
+	fmt.Println()
+	responseparser.PrintBulkResultsTable(results)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d VMs failed to %s; first error: %w", failed, len(targets), action, firstErr)
+	}
+	return nil
+}
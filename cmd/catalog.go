@@ -8,43 +8,55 @@ import (
 	"github.com/jessegalley/vhicmd/api"
 	"github.com/jessegalley/vhicmd/internal/responseparser"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
-	flagInterface string // New flag to filter by interface
+	flagCatalogAll bool
 )
 
 var catalogCmd = &cobra.Command{
 	Use:     "catalog",
 	Aliases: []string{"cat"},
 	Short:   "Fetch and display the OpenStack service catalog",
-	Long:    "Fetches the service catalog from the OpenStack Identity API and displays the available services and their endpoints.",
+	Long: `Fetches the service catalog from the OpenStack Identity API and displays the available services and their endpoints.
+
+Endpoints for the selected interface (--interface or 'interface' in config,
+default public) are listed; the one vhicmd uses for each service, honouring
+--region or 'region' in config, is marked as selected.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		resp, err := api.GetCatalog(tok.Host, tok.Value)
 		if err != nil {
 			return err
 		}
 
-		// Filter by interface type (default: public)
-		filterInterface := strings.ToLower(flagInterface)
-		if filterInterface == "" {
-			filterInterface = "public" // Default to public
+		region := viper.GetString("region")
+		iface := strings.ToLower(viper.GetString("interface"))
+		if iface == "" {
+			iface = api.DefaultInterface
 		}
 
-		// Filter the catalog
+		catalog := resp.Endpoints()
+		selected := api.SelectEndpoints(catalog, region, iface)
+
 		var filteredCatalog []responseparser.CatalogEntry
-		for _, svc := range resp.Catalog {
-			for _, ep := range svc.Endpoints {
-				if strings.ToLower(ep.Interface) == filterInterface {
-					filteredCatalog = append(filteredCatalog, responseparser.CatalogEntry{
-						Type:      svc.Type,
-						Name:      svc.Name,
-						Interface: ep.Interface,
-						Region:    ep.Region,
-						URL:       ep.URL,
-					})
-				}
+		for _, ep := range catalog {
+			if !flagCatalogAll && strings.ToLower(ep.Interface) != iface {
+				continue
+			}
+			regionName := ep.RegionID
+			if regionName == "" {
+				regionName = ep.Region
 			}
+			filteredCatalog = append(filteredCatalog, responseparser.CatalogEntry{
+				Type:      ep.Type,
+				Name:      ep.Name,
+				Interface: ep.Interface,
+				Region:    regionName,
+				URL:       ep.URL,
+				Selected: strings.EqualFold(ep.Interface, iface) && ep.InRegion(region) &&
+					selected[ep.Type] == ep.URL,
+			})
 		}
 
 		// JSON output if flagJsonOutput is set
@@ -72,12 +84,10 @@ func init() {
 		"Output in JSON format (instead of a table).",
 	)
 
-	// Interface filter flag
-	catalogCmd.Flags().StringVarP(
-		&flagInterface,
-		"interface",
-		"i",
-		"public",
-		"Filter endpoints by interface type (public, private, admin).",
+	catalogCmd.Flags().BoolVar(
+		&flagCatalogAll,
+		"all",
+		false,
+		"Show endpoints for every interface, not just the selected one.",
 	)
 }
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jessegalley/vhicmd/api"
+)
+
+// cleanupTimeout bounds how long pending cleanups may take once a command
+// has failed or been interrupted.
+const cleanupTimeout = 2 * time.Minute
+
+// cleanupAction undoes one step of a command, e.g. deletes a temporary
+// image it created.
+type cleanupAction struct {
+	id   int
+	desc string
+	fn   func(ctx context.Context) error
+}
+
+var cleanups struct {
+	sync.Mutex
+	next    int
+	actions []cleanupAction
+}
+
+// onCleanup registers fn to run if the command fails or is interrupted.
+// Call the returned func once the resource is no longer temporary (it was
+// deleted normally, or handed over to something that owns it).
+func onCleanup(desc string, fn func(ctx context.Context) error) (release func()) {
+	cleanups.Lock()
+	defer cleanups.Unlock()
+
+	cleanups.next++
+	id := cleanups.next
+	cleanups.actions = append(cleanups.actions, cleanupAction{id: id, desc: desc, fn: fn})
+
+	return func() {
+		cleanups.Lock()
+		defer cleanups.Unlock()
+		for i, a := range cleanups.actions {
+			if a.id == id {
+				cleanups.actions = append(cleanups.actions[:i], cleanups.actions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// runCleanups runs pending cleanup actions, newest first, and forgets them.
+// It uses its own context since the command's may already be cancelled.
+func runCleanups() {
+	cleanups.Lock()
+	actions := cleanups.actions
+	cleanups.actions = nil
+	cleanups.Unlock()
+
+	if len(actions) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	for i := len(actions) - 1; i >= 0; i-- {
+		a := actions[i]
+		fmt.Fprintf(os.Stderr, "Cleaning up: %s\n", a.desc)
+		if err := a.fn(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cleanup failed (%s): %v\n", a.desc, err)
+		}
+	}
+}
+
+// errInterrupted is the cancellation cause when a signal stops a command.
+var errInterrupted = errors.New("interrupted")
+
+// signalContext returns a context cancelled with errInterrupted by the first
+// SIGINT or SIGTERM. After that the default signal behavior is restored, so
+// a second Ctrl-C kills the process even while cleanups run.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigs:
+			signal.Stop(sigs)
+			fmt.Fprintln(os.Stderr, "\nInterrupted; stopping (press Ctrl-C again to quit immediately)")
+			cancel(errInterrupted)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		cancel(nil)
+	}
+}
+
+// cleanupImage and friends register deletion of temporary resources.
+func cleanupImage(imageID string) func() {
+	return onCleanup("delete image "+imageID, func(ctx context.Context) error {
+		return api.NewClient(tok).Image().DeleteImage(ctx, imageID)
+	})
+}
+
+// cleanupVM waits for the VM to be gone, so that volumes and ports
+// registered before it are no longer attached when their cleanups run.
+func cleanupVM(vmID string) func() {
+	return onCleanup("delete VM "+vmID, func(ctx context.Context) error {
+		if err := api.NewClient(tok).Compute().DeleteServer(ctx, vmID); err != nil {
+			return err
+		}
+		return api.WaitForVMDeleted(ctx, tok.Endpoints["compute"], tok.Value, vmID, api.WaitOptions{})
+	})
+}
+
+// cleanupVolume waits for a volume that is still attached to be released
+// by its VM before deleting it.
+func cleanupVolume(volumeID string) func() {
+	return onCleanup("delete volume "+volumeID, func(ctx context.Context) error {
+		volumes := api.NewClient(tok).Volume()
+		if vol, err := volumes.GetVolume(ctx, volumeID); err == nil && (vol.Status == "in-use" || vol.Status == "detaching") {
+			err := api.WaitForVolumeStatus(ctx, tok.Endpoints["volumev3"], tok.Value, volumeID, "available", api.WaitOptions{})
+			if err != nil {
+				return err
+			}
+		}
+		return volumes.DeleteVolume(ctx, volumeID)
+	})
+}
+
+func cleanupPort(portID string) func() {
+	return onCleanup("delete port "+portID, func(ctx context.Context) error {
+		return api.NewClient(tok).Network().DeletePort(ctx, portID)
+	})
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/jessegalley/vhicmd/api"
 	"github.com/jessegalley/vhicmd/internal/config"
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+	"github.com/jessegalley/vhicmd/internal/responseparser"
+	"github.com/jessegalley/vhicmd/internal/secrets"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // Available config keys
@@ -18,6 +24,28 @@ var configKeys = []string{
 	"networks",
 	"flavor_id",
 	"image_id",
+	"region",
+	"interface",
+	"cacert",
+	"cert",
+	"key",
+	"insecure",
+	"proxy",
+	"retry_max_attempts",
+	"retry_base_delay",
+	"auth_method",
+	"application_credential_id",
+	"application_credential_secret",
+	"auth_token",
+	"secret_backend",
+	"credential_helper",
+}
+
+// secretConfigKeys are never printed by config list/get
+var secretConfigKeys = map[string]bool{
+	"password":                      true,
+	"application_credential_secret": true,
+	"auth_token":                    true,
 }
 
 var configCmd = &cobra.Command{
@@ -37,17 +65,15 @@ var configListCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if activeContext != "" {
+			if err := config.ApplyContext(v, activeContext); err != nil {
+				return err
+			}
+			fmt.Printf("context: %s\n", activeContext)
+		}
 
-		settings := v.AllSettings()
 		for _, key := range configKeys {
-			value := settings[key]
-			if value == nil || value == "" {
-				fmt.Printf("%s: UNSET\n", key)
-			} else if key == "password" && value != "" {
-				fmt.Printf("%s: ********\n", key)
-			} else {
-				fmt.Printf("%s: %v\n", key, value)
-			}
+			fmt.Printf("%s: %s\n", key, displayConfigValue(v, key))
 		}
 		return nil
 	},
@@ -79,13 +105,41 @@ var configSetCmd = &cobra.Command{
 			return err
 		}
 
-		v.Set(key, value)
+		if key == "secret_backend" && value != "" && value != "plaintext" && value != secretBackendPassphrase {
+			return fmt.Errorf("invalid secret_backend '%s'; use 'plaintext' or '%s'", value, secretBackendPassphrase)
+		}
+		if key == "secret_backend" && value == secretBackendPassphrase {
+			return fmt.Errorf("use 'vhicmd config encrypt' to enable the passphrase backend")
+		}
+		if key == "proxy" && value != "" {
+			if _, err := httpclient.ParseProxy(value); err != nil {
+				return err
+			}
+		}
+
+		stored := value
+		if secretConfigKeys[key] && v.GetString("secret_backend") == secretBackendPassphrase {
+			stored, err = sealConfigSecret(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Context settings go into the active context
+		if activeContext != "" && config.IsContextKey(key) {
+			v.Set(config.ContextSettingKey(activeContext, key), stored)
+		} else {
+			v.Set(key, stored)
+		}
 		if err := v.WriteConfig(); err != nil {
 			if err := v.SafeWriteConfig(); err != nil {
 				return fmt.Errorf("error writing config: %v", err)
 			}
 		}
 
+		if secretConfigKeys[key] {
+			value = "********"
+		}
 		fmt.Printf("Set %s = %s\n", key, value)
 		return nil
 	},
@@ -115,23 +169,304 @@ var configGetCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if activeContext != "" {
+			if err := config.ApplyContext(v, activeContext); err != nil {
+				return err
+			}
+		}
 
-		value := v.Get(key)
-		if value == nil || value == "" {
-			fmt.Printf("%s: UNSET\n", key)
-		} else if key == "password" {
-			fmt.Printf("%s: ********\n", key)
-		} else {
-			fmt.Printf("%s: %v\n", key, value)
+		fmt.Printf("%s: %s\n", key, displayConfigValue(v, key))
+		return nil
+	},
+}
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt stored secrets and the token file with a passphrase",
+	Long: `Encrypts the password, application credential secret and auth token in the
+config, and the cached token file, with a key derived from a passphrase
+(PBKDF2-HMAC-SHA256, AES-256-GCM). Sets secret_backend to 'passphrase'.
+
+The passphrase is read from VHICMD_PASSPHRASE or prompted for. Commands that
+need a secret or a token will ask for it once per run.
+
+To keep secrets out of the config entirely, set credential_helper instead: a
+command run as "<helper> get <key>" that prints the secret, e.g.
+  vhicmd config set credential_helper "pass-vhicmd"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, _ := cmd.Flags().GetString("config")
+		v, err := config.InitConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		passphrase, err := getPassphrase(true)
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		for _, key := range configKeys {
+			if secretConfigKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+		contexts, err := config.ListContexts(v)
+		if err != nil {
+			return err
+		}
+		for _, c := range contexts {
+			for _, key := range config.ContextKeys {
+				if secretConfigKeys[key] {
+					keys = append(keys, config.ContextSettingKey(c.Name, key))
+				}
+			}
+		}
+
+		encrypted := 0
+		for _, key := range keys {
+			value := v.GetString(key)
+			if value == "" || secrets.IsEncrypted(value) {
+				continue
+			}
+			sealed, err := secrets.Encrypt([]byte(value), passphrase)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt '%s': %v", key, err)
+			}
+			v.Set(key, sealed)
+			encrypted++
+		}
+
+		v.Set("secret_backend", secretBackendPassphrase)
+		if err := v.WriteConfig(); err != nil {
+			return fmt.Errorf("error writing config: %v", err)
+		}
+
+		api.TokenCipher = passphraseCipher{}
+		if err := api.RewriteTokenFile(); err != nil {
+			return fmt.Errorf("failed to encrypt token file: %v", err)
+		}
+
+		fmt.Printf("Encrypted %d secret(s) in %s and the token file %s\n", encrypted, v.ConfigFileUsed(), api.TokenFile)
+		return nil
+	},
+}
+
+var configUseContextCmd = &cobra.Command{
+	Use:   "use-context <name>",
+	Short: "Set the default context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, _ := cmd.Flags().GetString("config")
+		name := strings.ToLower(args[0])
+
+		v, err := config.InitConfig(configFile)
+		if err != nil {
+			return err
+		}
+		if err := config.ValidateContextName(name); err != nil {
+			return err
+		}
+		if !config.HasContext(v, name) {
+			return fmt.Errorf("context '%s' not found in config", name)
+		}
+
+		v.Set(config.CurrentContextKey, name)
+		if err := v.WriteConfig(); err != nil {
+			return fmt.Errorf("error writing config: %v", err)
+		}
+
+		fmt.Printf("Switched to context \"%s\"\n", name)
+		return nil
+	},
+}
+
+var configGetContextsCmd = &cobra.Command{
+	Use:     "get-contexts",
+	Aliases: []string{"contexts"},
+	Short:   "List the contexts in the config",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, _ := cmd.Flags().GetString("config")
+		v, err := config.InitConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		contexts, err := config.ListContexts(v)
+		if err != nil {
+			return err
+		}
+		if len(contexts) == 0 {
+			fmt.Println("No contexts defined; create one with 'vhicmd config set-context'")
+			return nil
+		}
+
+		current := v.GetString(config.CurrentContextKey)
+		if activeContext != "" {
+			current = activeContext
+		}
+
+		var entries []responseparser.ContextEntry
+		for _, c := range contexts {
+			entries = append(entries, responseparser.ContextEntry{
+				Current:  c.Name == current,
+				Name:     c.Name,
+				Host:     c.Host,
+				Domain:   c.Domain,
+				Project:  c.Project,
+				Username: c.Username,
+			})
+		}
+
+		if flagJsonOutput {
+			b, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Println(string(b))
+			return nil
+		}
+
+		responseparser.PrintContextsTable(entries)
+		return nil
+	},
+}
+
+var (
+	flagCtxHost     string
+	flagCtxDomain   string
+	flagCtxProject  string
+	flagCtxUsername string
+	flagCtxNetworks string
+	flagCtxFlavor   string
+	flagCtxImage    string
+	flagCtxRegion   string
+	flagCtxIface    string
+)
+
+var configSetContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create or update a context",
+	Long: `Creates a named context, or updates the given settings of an existing one.
+  Examples:
+    vhicmd config set-context staging --host vhi-staging.example.com --domain mydomain --project dev
+    vhicmd config set-context prod --host vhi.example.com --domain mydomain --project web
+    vhicmd config use-context staging
+    vhicmd --context prod list vms`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, _ := cmd.Flags().GetString("config")
+		name := strings.ToLower(args[0])
+		if err := config.ValidateContextName(name); err != nil {
+			return err
 		}
+
+		v, err := config.InitConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		settings := map[string]string{
+			"host":      flagCtxHost,
+			"domain":    flagCtxDomain,
+			"project":   flagCtxProject,
+			"username":  flagCtxUsername,
+			"networks":  flagCtxNetworks,
+			"flavor_id": flagCtxFlavor,
+			"image_id":  flagCtxImage,
+			"region":    flagCtxRegion,
+			"interface": flagCtxIface,
+		}
+		// Make sure an empty context still exists in the file
+		if !config.HasContext(v, name) {
+			v.Set("contexts."+name, map[string]interface{}{})
+		}
+		for key, value := range settings {
+			if cmd.Flags().Changed(contextFlagName(key)) {
+				v.Set(config.ContextSettingKey(name, key), value)
+			}
+		}
+
+		if err := v.WriteConfig(); err != nil {
+			return fmt.Errorf("error writing config: %v", err)
+		}
+
+		fmt.Printf("Context \"%s\" saved\n", name)
 		return nil
 	},
 }
 
+// contextFlagName maps a context setting to its set-context flag.
+func contextFlagName(key string) string {
+	switch key {
+	case "flavor_id":
+		return "flavor"
+	case "image_id":
+		return "image"
+	}
+	return key
+}
+
+// writeConfigValues saves settings to the config file. Context settings go
+// into the active context, if there is one.
+func writeConfigValues(values map[string]string) error {
+	v, err := config.InitConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		if activeContext != "" && config.IsContextKey(key) {
+			v.Set(config.ContextSettingKey(activeContext, key), value)
+		} else {
+			v.Set(key, value)
+		}
+		viper.Set(key, value)
+	}
+
+	if err := v.WriteConfig(); err != nil {
+		if err := v.SafeWriteConfig(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayConfigValue formats a config value for list/get, never revealing secrets.
+func displayConfigValue(v *viper.Viper, key string) string {
+	value := v.GetString(key)
+	switch {
+	case secretConfigKeys[key] && secrets.IsEncrypted(value):
+		return "******** (encrypted)"
+	case secretConfigKeys[key] && value == "" && v.GetString("credential_helper") != "":
+		return "******** (credential helper)"
+	case value == "":
+		return "UNSET"
+	case secretConfigKeys[key]:
+		return "********"
+	}
+	return value
+}
+
 func init() {
 
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEncryptCmd)
+	configCmd.AddCommand(configUseContextCmd)
+	configCmd.AddCommand(configGetContextsCmd)
+	configCmd.AddCommand(configSetContextCmd)
+
+	configGetContextsCmd.Flags().BoolVar(&flagJsonOutput, "json", false, "Output in JSON format (instead of a table).")
+
+	configSetContextCmd.Flags().StringVar(&flagCtxHost, "host", "", "VHI host")
+	configSetContextCmd.Flags().StringVar(&flagCtxDomain, "domain", "", "VHI domain")
+	configSetContextCmd.Flags().StringVar(&flagCtxProject, "project", "", "VHI project")
+	configSetContextCmd.Flags().StringVar(&flagCtxUsername, "username", "", "VHI username")
+	configSetContextCmd.Flags().StringVar(&flagCtxNetworks, "networks", "", "Default networks for VM creation (CSV)")
+	configSetContextCmd.Flags().StringVar(&flagCtxFlavor, "flavor", "", "Default flavor for VM creation")
+	configSetContextCmd.Flags().StringVar(&flagCtxImage, "image", "", "Default image for VM creation")
+	configSetContextCmd.Flags().StringVar(&flagCtxRegion, "region", "", "Region whose endpoints to use")
+	configSetContextCmd.Flags().StringVar(&flagCtxIface, "interface", "", "Endpoint interface: public, internal or admin")
 	rootCmd.AddCommand(configCmd)
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -47,12 +48,11 @@ var createImageCmd = &cobra.Command{
 			}
 
 			// Verify the instance exists
-			id, err := api.GetVMIDByName(computeURL, tok.Value, flagInstanceID)
-			if err == nil {
-				flagInstanceID = id
-			} else {
-				return fmt.Errorf("instance not found: %v", err)
+			id, err := resolveID(cmd, resolver.KindVM, flagInstanceID)
+			if err != nil {
+				return err
 			}
+			flagInstanceID = id
 
 			// If no name provided, use instance name + timestamp
 			if flagImageName == "" {
@@ -85,59 +85,22 @@ var createImageCmd = &cobra.Command{
 			if imageID == "" {
 				return fmt.Errorf("failed to get a valid image ID from volume upload")
 			}
+			releaseImage := cleanupImage(imageID)
 
 			fmt.Printf("Waiting for image (ID: %s) to become active...\n", imageID)
 
-			// Monitor image status until it's active
-			maxAttempts := 60 // 5 minutes with 5 second intervals
-			var lastStatus string
-			for attempt := 0; attempt < maxAttempts; attempt++ {
-				// List all images and find the one with our ID
-				images, err := api.ListImages(imageURL, tok.Value, map[string]string{"id": imageID})
-				if err != nil {
-					fmt.Printf("Warning: Error checking image status: %v (retrying...)\n", err)
-					time.Sleep(5 * time.Second)
-					continue
-				}
-
-				var found bool
-				var status string
-
-				for _, img := range images.Images {
-					if img.ID == imageID {
-						found = true
-						status = img.Status
-						break
-					}
-				}
-
-				if !found {
-					if lastStatus != "not_found" {
-						fmt.Printf("Image not found yet, waiting...\n")
-						lastStatus = "not_found"
-					}
-					time.Sleep(5 * time.Second)
-					continue
-				}
-
-				if status != lastStatus {
-					fmt.Printf("Image status: %s\n", status)
-					lastStatus = status
-				}
-
-				if status == "active" {
-					fmt.Printf("Image is now active\n")
-					break
-				} else if status == "error" {
-					return fmt.Errorf("image creation failed with status: error")
-				}
-
-				if attempt == maxAttempts-1 {
-					return fmt.Errorf("timed out waiting for image to become active")
-				}
+			if flagNoWait {
+				releaseImage()
+				fmt.Printf("Image %s is being created; check it with 'vhicmd details image %s'\n", imageID, imageID)
+				return nil
+			}
 
-				time.Sleep(5 * time.Second)
+			if _, err := api.WaitForImageStatus(cmd.Context(), imageURL, tok.Value, imageID, "active", waitOptions("Image")); err != nil {
+				return err
 			}
+			fmt.Printf("Image is now active\n")
+
+			releaseImage()
 
 			fmt.Printf("Image created successfully: ID: %s, Name: %s\n", imageID, flagImageName)
 			return nil
@@ -239,7 +202,7 @@ var createImageCmd = &cobra.Command{
 			Visibility:   "shared",
 		}
 
-		imageID, err := api.CreateAndUploadImage(imageURL, tok.Value, req, file)
+		imageID, err := api.CreateAndUploadImage(cmd.Context(), imageURL, tok.Value, req, file)
 		if err != nil {
 			return fmt.Errorf("failed to create/upload image: %v", err)
 		}
@@ -266,9 +229,9 @@ var createVolumeCmd = &cobra.Command{
 				return err
 			}
 
-			imageID, err := api.GetImageIDByName(imageURL, tok.Value, flagVolumeImage)
+			imageID, err := resolveID(cmd, resolver.KindImage, flagVolumeImage)
 			if err != nil {
-				return fmt.Errorf("failed to get image ID: %v", err)
+				return err
 			}
 
 			imageSize, err := api.GetImageSize(imageURL, tok.Value, imageID)
@@ -282,6 +245,9 @@ var createVolumeCmd = &cobra.Command{
 			if err != nil {
 				return err
 			}
+			if err := waitForNewVolume(cmd, storageURL, resp.Volume.ID); err != nil {
+				return err
+			}
 			fmt.Printf("Volume created from image: ID: %s, Name: %s, Size: %d GB\n", resp.Volume.ID, resp.Volume.Name, resp.Volume.Size)
 			return nil
 		}
@@ -296,12 +262,25 @@ var createVolumeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if err := waitForNewVolume(cmd, storageURL, resp.Volume.ID); err != nil {
+			return err
+		}
 
 		fmt.Printf("Volume created: ID: %s, Name: %s, Size: %d GB\n", resp.Volume.ID, resp.Volume.Name, resp.Volume.Size)
 		return nil
 	},
 }
 
+// waitForNewVolume waits for a volume just created to become available,
+// unless --no-wait was given. Copying a large image can take a while.
+func waitForNewVolume(cmd *cobra.Command, storageURL, volumeID string) error {
+	if flagNoWait {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Waiting for volume %s to become available...\n", volumeID)
+	return api.WaitForVolumeStatus(cmd.Context(), storageURL, tok.Value, volumeID, "available", waitOptions("Volume"))
+}
+
 var createPortCmd = &cobra.Command{
 	Use:     "port",
 	Aliases: []string{"nic", "interface"},
@@ -322,14 +301,9 @@ var createPortCmd = &cobra.Command{
 			return err
 		}
 
-		// Check if network exists by name first
-		fmt.Fprintf(os.Stderr, "Checking network ID for %s\n", networkID)
-		netID, err := api.GetNetworkIDByName(networkURL, tok.Value, networkID)
-		if err == nil {
-			fmt.Fprintf(os.Stderr, "Network found: %s\n", netID)
-			networkID = netID
-		} else {
-			fmt.Fprintf(os.Stderr, "Network ID not found by name, using as-is: %s\n", err)
+		networkID, err = resolveID(cmd, resolver.KindNetwork, networkID)
+		if err != nil {
+			return err
 		}
 
 		// Build fixed IPs if --ip was provided
@@ -439,6 +413,10 @@ func init() {
 	createImageCmd.Flags().StringVar(&flagInstanceID, "instance", "", "VM instance ID or name to snapshot")
 	createImageCmd.Flags().BoolVar(&flagDeleteSnapshot, "delete-snapshot", true, "Delete snapshot after creating template")
 
+	for _, c := range []*cobra.Command{createVMCmd, createVolumeCmd, createImageCmd} {
+		addWaitFlags(c, true)
+	}
+
 	// Flags for create port
 	createPortCmd.Flags().StringVar(&flagPortNetwork, "network", "", "Network ID or name")
 	createPortCmd.Flags().StringVar(&flagPortMAC, "mac", "", "MAC address")
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/jessegalley/vhicmd/internal/template"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -29,12 +30,10 @@ var createVMCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		networkURL, err := validateTokenEndpoint(tok, "network")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "network"); err != nil {
 			return err
 		}
-		imageURL, err := validateTokenEndpoint(tok, "image")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "image"); err != nil {
 			return err
 		}
 
@@ -132,10 +131,11 @@ var createVMCmd = &cobra.Command{
 			}
 
 			for i, netName := range networkIDs {
-				nid, err := api.GetNetworkIDByName(networkURL, tok.Value, netName)
-				if err == nil {
-					networkIDs[i] = nid
+				nid, err := resolveID(cmd, resolver.KindNetwork, netName)
+				if err != nil {
+					return err
 				}
+				networkIDs[i] = nid
 			}
 
 			var netSlice []map[string]interface{}
@@ -177,11 +177,13 @@ var createVMCmd = &cobra.Command{
 		//----------------------------------------------------------------
 		// 4. Resolve image & flavor by name if necessary
 		//----------------------------------------------------------------
-		if imgID, err := api.GetImageIDByName(imageURL, tok.Value, imageRef); err == nil && imgID != "" {
-			imageRef = imgID
+		if imageRef != "" {
+			if imageRef, err = resolveID(cmd, resolver.KindImage, imageRef); err != nil {
+				return err
+			}
 		}
-		if fid, err := api.GetFlavorIDByName(computeURL, tok.Value, flavorRef); err == nil && fid != "" {
-			flavorRef = fid
+		if flavorRef, err = resolveID(cmd, resolver.KindFlavor, flavorRef); err != nil {
+			return err
 		}
 
 		//----------------------------------------------------------------
@@ -191,17 +193,15 @@ var createVMCmd = &cobra.Command{
 		if flagVMSize > 0 {
 			volumeSize = flagVMSize
 		}
-		if flagCIData != "" && flagCIDataFile != "" {
-			return fmt.Errorf("--ci-data and --ci-data-file are mutually exclusive")
-		}
-		if (flagCIData != "" || flagCIDataFile != "") && flagUserData == "" {
-			return fmt.Errorf("--ci-data/--ci-data-file requires --user-data")
+		if err := checkUserDataFlags(); err != nil {
+			return err
 		}
 
 		//----------------------------------------------------------------
 		// 6. Create the base VM request
 		//----------------------------------------------------------------
 		var request api.CreateVMRequest
+		releaseBootVolume := func() {}
 		request.Server.Name = flagVMName
 		request.Server.FlavorRef = flavorRef
 
@@ -238,57 +238,17 @@ var createVMCmd = &cobra.Command{
 			// 13. Cloud-init / user data (templating if needed)
 			//------------------------------------------------------------
 			if flagUserData != "" {
-				var userData string
-				if flagCIData != "" || flagCIDataFile != "" {
-					// Templating path
-					var ciDataStr string
-					if flagCIDataFile != "" {
-						fileBytes, err := os.ReadFile(flagCIDataFile)
-						if err != nil {
-							return fmt.Errorf("error reading ci-data-file: %v", err)
-						}
-						ciDataStr = string(fileBytes)
-					} else {
-						ciDataStr = flagCIData
-					}
-					ciData, err := template.ParseKeyValueString(ciDataStr)
-					if err != nil {
-						return fmt.Errorf("error parsing ci-data: %v", err)
-					}
-
-					rawUserData, err := readUserDataFile(flagUserData)
-					if err != nil {
-						return err
-					}
-
-					validation := template.ValidateTemplate(rawUserData, ciData)
-					if !validation.Valid {
-						return fmt.Errorf("template validation failed: missing vars %v", validation.MissingVariables)
-					}
-					if len(validation.UnusedVariables) > 0 {
-						return fmt.Errorf("template validation failed: unused vars %v", validation.UnusedVariables)
-					}
-
-					processedUserData := template.ReplaceVariables(rawUserData, ciData)
-					userData, err = encodeUserData(processedUserData)
-					if err != nil {
-						return err
-					}
-
-				} else {
-					// Plain user-data, no templating
-					userData, err = readAndEncodeUserData(flagUserData)
-					if err != nil {
-						return err
-					}
+				userData, err := userDataFromFlags()
+				if err != nil {
+					return err
 				}
-
 				request.Server.UserData = userData
 				request.Server.ConfigDrive = true
 			}
 
 		} else {
-			// Netboot or no image => create blank volume
+			// Netboot or no image => create blank volume. It is removed
+			// again if we fail before the VM takes it over.
 			fmt.Fprintf(os.Stderr, "Creating blank boot volume for VM %s...\n", flagVMName)
 			volRequest := api.CreateVolumeRequest{}
 			volRequest.Volume.Name = fmt.Sprintf("%s-boot", flagVMName)
@@ -300,9 +260,10 @@ var createVMCmd = &cobra.Command{
 			if err != nil {
 				return fmt.Errorf("failed to create blank boot volume: %v", err)
 			}
+			releaseBootVolume = cleanupVolume(volResp.Volume.ID)
 			fmt.Fprintf(os.Stderr, "Waiting for volume to become available...\n")
 
-			if err := api.WaitForVolumeStatus(storageURL, tok.Value, volResp.Volume.ID, "available"); err != nil {
+			if err := api.WaitForVolumeStatus(cmd.Context(), storageURL, tok.Value, volResp.Volume.ID, "available", waitOptions("Volume")); err != nil {
 				return fmt.Errorf("failed waiting for volume: %v", err)
 			}
 
@@ -345,11 +306,17 @@ var createVMCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create VM: %v", err)
 		}
+		releaseBootVolume() // deleted with the VM from here on
 
 		//----------------------------------------------------------------
-		// 15. Wait for VM to become ACTIVE
+		// 15. Wait for VM to become ACTIVE, or just report it with --no-wait
 		//----------------------------------------------------------------
-		vmDetails, err := api.WaitForStatus(computeURL, tok.Value, resp.Server.ID, "ACTIVE")
+		var vmDetails api.VMDetail
+		if flagNoWait {
+			vmDetails, err = api.GetVMDetails(computeURL, tok.Value, resp.Server.ID)
+		} else {
+			vmDetails, err = api.WaitForStatus(cmd.Context(), computeURL, tok.Value, resp.Server.ID, waitOptions("VM"), "ACTIVE")
+		}
 		if err != nil {
 			return err
 		}
@@ -438,6 +405,60 @@ var createVMCmd = &cobra.Command{
 	},
 }
 
+// checkUserDataFlags checks that --ci-data and --ci-data-file are used
+// correctly, before anything is created.
+func checkUserDataFlags() error {
+	if flagCIData != "" && flagCIDataFile != "" {
+		return fmt.Errorf("--ci-data and --ci-data-file are mutually exclusive")
+	}
+	if (flagCIData != "" || flagCIDataFile != "") && flagUserData == "" {
+		return fmt.Errorf("--ci-data/--ci-data-file requires --user-data")
+	}
+	return nil
+}
+
+// userDataFromFlags reads the --user-data file and, with --ci-data or
+// --ci-data-file, fills in its template variables. It returns the result
+// base64-encoded for Nova.
+func userDataFromFlags() (string, error) {
+	if flagCIData == "" && flagCIDataFile == "" {
+		// Plain user-data, no templating
+		return readAndEncodeUserData(flagUserData)
+	}
+
+	// Templating path
+	var ciDataStr string
+	if flagCIDataFile != "" {
+		fileBytes, err := os.ReadFile(flagCIDataFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading ci-data-file: %v", err)
+		}
+		ciDataStr = string(fileBytes)
+	} else {
+		ciDataStr = flagCIData
+	}
+	ciData, err := template.ParseKeyValueString(ciDataStr)
+	if err != nil {
+		return "", fmt.Errorf("error parsing ci-data: %v", err)
+	}
+
+	rawUserData, err := readUserDataFile(flagUserData)
+	if err != nil {
+		return "", err
+	}
+
+	validation := template.ValidateTemplate(rawUserData, ciData)
+	if !validation.Valid {
+		return "", fmt.Errorf("template validation failed: missing vars %v", validation.MissingVariables)
+	}
+	if len(validation.UnusedVariables) > 0 {
+		return "", fmt.Errorf("template validation failed: unused vars %v", validation.UnusedVariables)
+	}
+
+	processedUserData := template.ReplaceVariables(rawUserData, ciData)
+	return encodeUserData(processedUserData)
+}
+
 var (
 	flagVMName     string
 	flagFlavorRef  string
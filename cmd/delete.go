@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/jessegalley/vhicmd/internal/responseparser"
 	"github.com/spf13/cobra"
 )
 
@@ -13,35 +17,224 @@ var deleteCmd = &cobra.Command{
 	Short:   "Delete resources",
 }
 
+var (
+	flagDeleteDryRun  bool
+	flagDeleteCascade bool
+)
+
 var deleteVMCmd = &cobra.Command{
-	Use:     "vm <vm_id>",
+	Use:     "vm [vm_id]...",
 	Aliases: []string{"instance"},
-	Short:   "Delete a VM",
-	Args:    cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		vmID := args[0]
+	Short:   "Delete VMs",
+	Long: `Delete one or more VMs, given by name or ID, or picked with --selector,
+--metadata or --from-file.
 
+The VMs and their attached volumes and ports are listed, along with what will
+happen to each, and you are asked to confirm unless --yes is given. Nova
+deletes delete_on_termination volumes and the ports it created itself; with
+--cascade, the other volumes and any pre-created ports are deleted too, once
+the VM is gone. --dry-run only shows the list.
+
+Example:
+  vhicmd delete vm web-01
+  vhicmd delete vm web-01 --cascade --dry-run
+  vhicmd delete vm --selector 'name=web-*' --metadata env=staging --parallel 4`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		computeURL, err := validateTokenEndpoint(tok, "compute")
 		if err != nil {
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		targets, _, err := selectVMs(cmd, args)
+		if err != nil {
+			return err
 		}
 
-		err = api.DeleteVM(computeURL, tok.Value, vmID)
-		if err != nil {
+		plans := make(map[string]vmDeletePlan, len(targets))
+		var items []responseparser.DeleteItem
+		for i, t := range targets {
+			plan, err := planVMDelete(cmd.Context(), t)
+			if err != nil {
+				return err
+			}
+			plans[t.ID] = plan
+			targets[i] = plan.VM
+			items = append(items, plan.items()...)
+		}
+		responseparser.PrintDeleteItemsTable(items)
+
+		if flagDeleteDryRun {
+			fmt.Println("Dry run; nothing was deleted.")
+			return nil
+		}
+		prompt := fmt.Sprintf("Delete VM %s? [y/N]: ", targets[0])
+		if len(targets) > 1 {
+			prompt = fmt.Sprintf("Delete %d VMs? [y/N]: ", len(targets))
+		}
+		if err := confirm(prompt); err != nil {
 			return err
 		}
 
-		fmt.Printf("VM %s deleted\n", vmID)
+		var mu sync.Mutex
+		var report []responseparser.DeleteItem
+		err = runOnVMs(cmd, "delete", targets, false, func(ctx context.Context, t vmTarget) error {
+			if err := api.NewClient(tok).Compute().DeleteServer(ctx, t.ID); err != nil {
+				return err
+			}
+			if !flagDeleteCascade {
+				fmt.Printf("VM %s deleted\n", t.ID)
+				return nil
+			}
 
-		return nil
+			items, err := plans[t.ID].cascade(ctx, computeURL)
+			mu.Lock()
+			report = append(report, items...)
+			mu.Unlock()
+			return err
+		})
+		if len(report) > 0 {
+			fmt.Println("\nRemoved:")
+			responseparser.PrintDeleteItemsTable(report)
+		}
+		return err
 	},
 }
 
+// vmDeletePlan is a VM about to be deleted and what is attached to it.
+type vmDeletePlan struct {
+	VM        vmTarget
+	Resources []vmResource
+}
+
+// vmResource is a volume or port attached to a VM.
+type vmResource struct {
+	Type string // "volume" or "port"
+	ID   string
+	Name string
+
+	// WithVM is set for volumes Nova deletes along with the VM
+	// (delete_on_termination); Shared for volumes also attached elsewhere,
+	// which are never deleted.
+	WithVM bool
+	Shared bool
+}
+
+// planVMDelete looks up the volumes and ports attached to t.
+func planVMDelete(ctx context.Context, t vmTarget) (vmDeletePlan, error) {
+	client := api.NewClient(tok)
+
+	vm, err := client.Compute().GetServer(ctx, t.ID)
+	if err != nil {
+		return vmDeletePlan{}, err
+	}
+	plan := vmDeletePlan{VM: vmTarget{ID: vm.ID, Name: vm.Name}}
+
+	for _, att := range vm.OSExtendedVolumesVolumesAttached {
+		vol, err := client.Volume().GetVolume(ctx, att.ID)
+		if err != nil {
+			return plan, fmt.Errorf("failed to get volume %s attached to VM %s: %v", att.ID, vm.ID, err)
+		}
+		plan.Resources = append(plan.Resources, vmResource{
+			Type:   "volume",
+			ID:     vol.ID,
+			Name:   vol.Name,
+			WithVM: att.DeleteOnTermination,
+			Shared: len(vol.Attachments) > 1,
+		})
+	}
+
+	ports, err := client.Network().ListPorts(ctx, map[string]string{"device_id": vm.ID})
+	if err != nil {
+		return plan, err
+	}
+	for _, p := range ports.Ports {
+		plan.Resources = append(plan.Resources, vmResource{Type: "port", ID: p.ID, Name: p.Name})
+	}
+	return plan, nil
+}
+
+// items lists the VM and its resources with what deleting it will do.
+func (p vmDeletePlan) items() []responseparser.DeleteItem {
+	items := []responseparser.DeleteItem{{Type: "vm", Name: p.VM.Name, ID: p.VM.ID, Action: "delete"}}
+	for _, r := range p.Resources {
+		item := responseparser.DeleteItem{Type: r.Type, Name: r.Name, ID: r.ID}
+		switch {
+		case r.Type == "port" && flagDeleteCascade:
+			item.Action = "delete"
+		case r.Type == "port":
+			item.Action = "deleted with VM unless pre-created"
+		case r.WithVM:
+			item.Action = "deleted with VM"
+		case r.Shared:
+			item.Action = "keep (attached to other VMs)"
+		case flagDeleteCascade:
+			item.Action = "delete"
+		default:
+			item.Action = "keep"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// cascade waits for the VM to be gone, then deletes the volumes and ports
+// Nova left behind. Every resource is tried; the report says what happened
+// to each.
+func (p vmDeletePlan) cascade(ctx context.Context, computeURL string) ([]responseparser.DeleteItem, error) {
+	client := api.NewClient(tok)
+	report := []responseparser.DeleteItem{{Type: "vm", Name: p.VM.Name, ID: p.VM.ID, Action: "deleted"}}
+
+	if err := api.WaitForVMDeleted(ctx, computeURL, tok.Value, p.VM.ID, waitOptions("VM "+p.VM.ID)); err != nil {
+		report[0].Action = "delete requested"
+		return report, fmt.Errorf("VM %s was not deleted, so its volumes and ports were kept: %w", p.VM.ID, err)
+	}
+
+	var failed int
+	for _, r := range p.Resources {
+		item := responseparser.DeleteItem{Type: r.Type, Name: r.Name, ID: r.ID, Action: "deleted"}
+		var err error
+		switch {
+		case r.Type == "port":
+			// Nova deletes the ports it created and only unbinds the rest
+			_, err = client.Network().GetPort(ctx, r.ID)
+			if api.IsNotFound(err) {
+				item.Action, err = "deleted with VM", nil
+			} else if err == nil {
+				err = client.Network().DeletePort(ctx, r.ID)
+			}
+		case r.WithVM:
+			item.Action = "deleted with VM"
+		case r.Shared:
+			item.Action = "kept (attached to other VMs)"
+		default:
+			err = deleteDetachedVolume(ctx, r.ID)
+		}
+		if err != nil {
+			item.Action = "failed: " + err.Error()
+			failed++
+		}
+		report = append(report, item)
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("failed to delete %d volumes or ports of VM %s", failed, p.VM.ID)
+	}
+	return report, nil
+}
+
+// deleteDetachedVolume waits for a volume to be detached from a deleted VM
+// and deletes it.
+func deleteDetachedVolume(ctx context.Context, volumeID string) error {
+	storageURL, err := validateTokenEndpoint(tok, "volumev3")
+	if err != nil {
+		return err
+	}
+	if err := api.WaitForVolumeStatus(ctx, storageURL, tok.Value, volumeID, "available", waitOptions("Volume "+volumeID)); err != nil {
+		return err
+	}
+	return api.NewClient(tok).Volume().DeleteVolume(ctx, volumeID)
+}
+
 var deleteImageCmd = &cobra.Command{
 	Use:     "image <image_id>",
 	Aliases: []string{"img"},
@@ -55,10 +248,8 @@ var deleteImageCmd = &cobra.Command{
 			return err
 		}
 
-		img, err := api.GetImageIDByName(computeURL, tok.Value, imageID)
-		if err == nil {
-			imageID = img
-		} else {
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
 			return err
 		}
 
@@ -86,6 +277,11 @@ var deleteVolumeCmd = &cobra.Command{
 			return err
 		}
 
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
+		}
+
 		err = api.DeleteVolume(blockURL, tok.Value, volumeID)
 		if err != nil {
 			return err
@@ -123,12 +319,9 @@ var deletePortCmd = &cobra.Command{
 			}
 			portID = resp.Ports[0].ID
 		} else {
-			// Try as name first, then fall back to UUID
-			id, err := api.GetPortIDByName(networkURL, tok.Value, identifier)
-			if err == nil {
-				portID = id
-			} else {
-				portID = identifier
+			portID, err = resolveID(cmd, resolver.KindPort, identifier)
+			if err != nil {
+				return err
 			}
 		}
 
@@ -155,9 +348,9 @@ var deleteImageMemberCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		err = api.RemoveImageMember(imageURL, tok.Value, imageID, projectID)
@@ -172,6 +365,10 @@ var deleteImageMemberCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+	addBulkFlags(deleteVMCmd)
+	addWaitFlags(deleteVMCmd, false)
+	deleteVMCmd.Flags().BoolVar(&flagDeleteDryRun, "dry-run", false, "Show what would be deleted without deleting anything")
+	deleteVMCmd.Flags().BoolVar(&flagDeleteCascade, "cascade", false, "Also delete attached volumes and pre-created ports that Nova keeps")
 	deleteCmd.AddCommand(deleteVMCmd)
 	deleteCmd.AddCommand(deleteImageCmd)
 	deleteCmd.AddCommand(deleteVolumeCmd)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/jessegalley/vhicmd/internal/responseparser"
 	"github.com/spf13/cobra"
 )
@@ -27,9 +28,9 @@ var vmDetailsCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
 		vm, err := api.GetVMDetails(computeURL, tok.Value, vmID)
@@ -86,6 +87,7 @@ var vmDetailsCmd = &cobra.Command{
 				ExtraSpecs: vm.Flavor.ExtraSpecs,
 			},
 			Metadata: vm.Metadata,
+			Tags:     vm.Tags,
 		}
 
 		// Fetch network details (for managed networks)
@@ -163,6 +165,11 @@ var portDetailsCmd = &cobra.Command{
 			return err
 		}
 
+		portID, err = resolveID(cmd, resolver.KindPort, portID)
+		if err != nil {
+			return err
+		}
+
 		port, err := api.GetPortDetails(networkURL, tok.Value, portID)
 		if err != nil {
 			return err
@@ -226,10 +233,9 @@ var imageDetailsCmd = &cobra.Command{
 			return err
 		}
 
-		// Try to resolve name to ID first
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		details, err := api.GetImageDetails(imageURL, tok.Value, imageID)
@@ -303,9 +309,9 @@ var volumeDetailsCmd = &cobra.Command{
 			return err
 		}
 
-		v, err := api.GetVolumeIDByName(storageURL, tok.Value, volumeID)
-		if err == nil {
-			volumeID = v
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
 		}
 
 		volume, err := api.GetVolumeDetails(storageURL, tok.Value, volumeID)
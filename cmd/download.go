@@ -5,9 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"syscall"
-	"time"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
@@ -31,10 +31,9 @@ var downloadImageCmd = &cobra.Command{
 			return err
 		}
 
-		// Try to resolve name to ID first
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		// Create parent directories if they don't exist
@@ -42,7 +41,7 @@ var downloadImageCmd = &cobra.Command{
 			return fmt.Errorf("failed to create output directory: %v", err)
 		}
 
-		err = api.DownloadImage(imageURL, tok.Value, imageID, outputPath)
+		err = api.DownloadImage(cmd.Context(), imageURL, tok.Value, imageID, outputPath)
 		if err != nil {
 			return err
 		}
@@ -71,10 +70,9 @@ var downloadVolumeCmd = &cobra.Command{
 			return err
 		}
 
-		// Try to resolve name to ID first
-		id, err := api.GetVolumeIDByName(storageURL, tok.Value, volumeID)
-		if err == nil {
-			volumeID = id
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
 		}
 
 		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
@@ -108,40 +106,17 @@ var downloadVolumeCmd = &cobra.Command{
 
 		imageID := resp.OsVolumeUploadImage.ImageID
 		fmt.Printf("Image creation started with ID: %s\n", imageID)
+		releaseImage := cleanupImage(imageID)
 
 		fmt.Printf("Waiting for image to become active...\n")
-		maxAttempts := 60 // 5 minutes with 5 second intervals
-		var lastStatus string
-		for attempt := 0; attempt < maxAttempts; attempt++ {
-			image, err := api.GetImageDetails(imageURL, tok.Value, imageID)
-			if err != nil {
-				fmt.Printf("Warning: Error checking image status: %v (retrying...)\n", err)
-				time.Sleep(5 * time.Second)
-				continue
-			}
-
-			if image.Status != lastStatus {
-				fmt.Printf("Image status: %s\n", image.Status)
-				lastStatus = image.Status
-			}
-
-			if image.Status == "active" {
-				fmt.Printf("Image is now active\n")
-				break
-			} else if image.Status == "error" {
-				return fmt.Errorf("image creation failed with status: error")
-			}
-
-			if attempt == maxAttempts-1 {
-				return fmt.Errorf("timed out waiting for image to become active")
-			}
-
-			time.Sleep(5 * time.Second)
+		if _, err := api.WaitForImageStatus(cmd.Context(), imageURL, tok.Value, imageID, "active", waitOptions("Image")); err != nil {
+			return err
 		}
+		fmt.Printf("Image is now active\n")
 
 		// Download the image
 		fmt.Printf("Downloading image...\n")
-		err = api.DownloadImage(imageURL, tok.Value, imageID, outputPath)
+		err = api.DownloadImage(cmd.Context(), imageURL, tok.Value, imageID, outputPath)
 		if err != nil {
 			return fmt.Errorf("failed to download image: %v", err)
 		}
@@ -152,6 +127,7 @@ var downloadVolumeCmd = &cobra.Command{
 		if err != nil {
 			fmt.Printf("Warning: failed to delete temporary image: %v\n", err)
 		}
+		releaseImage()
 
 		fmt.Printf("Volume downloaded to %s\n", outputPath)
 		return nil
@@ -159,6 +135,10 @@ var downloadVolumeCmd = &cobra.Command{
 }
 
 func init() {
+	// The volume has to be converted before it can be downloaded, so
+	// there is no --no-wait here.
+	addWaitFlags(downloadVolumeCmd, false)
+
 	downloadCmd.AddCommand(downloadImageCmd)
 	downloadCmd.AddCommand(downloadVolumeCmd)
 	rootCmd.AddCommand(downloadCmd)
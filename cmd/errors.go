@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gookit/color"
+	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Exit codes, one per class of failure, so scripts can tell a missing
+// resource from a quota problem without parsing messages.
+const (
+	exitError     = 1 // anything not covered below
+	exitUsage     = 2 // bad flags or arguments
+	exitAuth      = 3 // 401/403: expired token or missing permission
+	exitNotFound  = 4 // 404, or a reference that matches nothing
+	exitAmbiguous = 5 // a reference that matches more than one resource
+	exitConflict  = 6 // 409: the resource is busy or in the wrong state
+	exitOverLimit = 7 // quota or rate limit
+	exitServer    = 8 // 5xx from the service
+	exitTimeout   = 9 // gave up waiting for a resource (--wait-timeout)
+
+	exitInterrupted = 130 // stopped by SIGINT or SIGTERM, as shells report it
+)
+
+// usageError marks errors caused by how the command was invoked.
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	var usage usageError
+	var notFound *resolver.NotFoundError
+	var ambiguous *resolver.AmbiguousError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &ambiguous):
+		return exitAmbiguous
+	case errors.As(err, &notFound), api.IsNotFound(err):
+		return exitNotFound
+	case api.IsUnauthorized(err), api.IsForbidden(err):
+		return exitAuth
+	case api.IsConflict(err):
+		return exitConflict
+	case api.IsOverLimit(err):
+		return exitOverLimit
+	case api.IsServerError(err):
+		return exitServer
+	case api.IsTimeout(err):
+		return exitTimeout
+	}
+	return exitError
+}
+
+// printError writes err to stderr, colored when stderr is a terminal.
+func printError(err error) {
+	msg := err.Error()
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		msg = color.Style{color.FgRed}.Render(msg)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+}
+
+// markUsageErrors wraps the argument validators of cmd and its children,
+// and the flag parser, so their failures exit with exitUsage.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			if err := args(c, a); err != nil {
+				return usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, c := range cmd.Commands() {
+		markUsageErrors(c)
+	}
+}
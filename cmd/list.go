@@ -3,11 +3,15 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/facette/natsort"
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/jessegalley/vhicmd/internal/responseparser"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +28,9 @@ var listCmd = &cobra.Command{
 var listDomainsCmd = &cobra.Command{
 	Use:   "domains",
 	Short: "List domains [Req: admin]",
+	Long: `List domains. Uses a system- or domain-scoped token if one is cached
+(see 'vhicmd auth --scope'), otherwise the current project token.`,
+	Annotations: map[string]string{annotationTokenScope: tokenScopeAdmin},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Call the API
 		resp, err := api.ListDomains(tok.Host, tok.Value)
@@ -97,8 +104,7 @@ var listFlavorsCmd = &cobra.Command{
 	Use:   "flavors",
 	Short: "List flavors",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		computeURL, err := validateTokenEndpoint(tok, "compute")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "compute"); err != nil {
 			return err
 		}
 
@@ -129,10 +135,11 @@ var listFlavorsCmd = &cobra.Command{
 			queryParams["is_public"] = isPublic
 		}
 
-		resp, err := api.ListFlavors(computeURL, tok.Value, queryParams)
+		flavors, err := listPages(cmd, api.NewClient(tok).Compute().FlavorPager(queryParams, flagPageSize))
 		if err != nil {
 			return err
 		}
+		resp := api.FlavorListResponse{Flavors: flavors}
 
 		if flagJsonOutput {
 			// JSON
@@ -164,8 +171,7 @@ var listImagesCmd = &cobra.Command{
 	Aliases: []string{"img", "image"},
 	Short:   "List virtual machine images",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		imageURL, err := validateTokenEndpoint(tok, "image")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "image"); err != nil {
 			return err
 		}
 
@@ -183,7 +189,11 @@ var listImagesCmd = &cobra.Command{
 			queryParams["marker"] = marker
 		}
 
-		resp, err := api.ListImages(imageURL, tok.Value, queryParams)
+		pageSize := flagPageSize
+		if _, hasLimit := queryParams["limit"]; !hasLimit && pageSize == 0 {
+			pageSize = api.DefaultPageSize
+		}
+		images, err := listPages(cmd, api.NewClient(tok).Image().ImagePager(queryParams, pageSize))
 		if err != nil {
 			return err
 		}
@@ -191,7 +201,7 @@ var listImagesCmd = &cobra.Command{
 		nameFilter, _ := cmd.Flags().GetString("name")
 
 		var imgList []responseparser.Image
-		for _, i := range resp.Images {
+		for _, i := range images {
 			if nameFilter == "" || strings.Contains(
 				strings.ToLower(i.Name),
 				strings.ToLower(nameFilter),
@@ -235,9 +245,9 @@ var listImageMembersCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		resp, err := api.ListImageMembers(imageURL, tok.Value, imageID)
@@ -284,7 +294,7 @@ var listNetworksCmd = &cobra.Command{
 			queryParams["status"] = status
 		}
 
-		resp, err := api.ListNetworks(networkURL, tok.Value, queryParams)
+		networks, err := listPages(cmd, api.NewClient(tok).Network().NetworkPager(queryParams, flagPageSize))
 		if err != nil {
 			return err
 		}
@@ -294,7 +304,7 @@ var listNetworksCmd = &cobra.Command{
 
 		// Filter networks based on name containing the filter string
 		var filteredNetworks []responseparser.Network
-		for _, n := range resp.Networks {
+		for _, n := range networks {
 			if nameFilter == "" || strings.Contains(strings.ToLower(n.Name), strings.ToLower(nameFilter)) {
 				CIDRs := ""
 				for _, subnetID := range n.SubnetIDs {
@@ -340,8 +350,7 @@ var listPortsCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		networkURL, err := validateTokenEndpoint(tok, "network")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "network"); err != nil {
 			return err
 		}
 
@@ -360,10 +369,11 @@ var listPortsCmd = &cobra.Command{
 			queryParams["status"] = status
 		}
 
-		resp, err := api.ListPorts(networkURL, tok.Value, queryParams)
+		ports, err := listPages(cmd, api.NewClient(tok).Network().PortPager(queryParams, flagPageSize))
 		if err != nil {
 			return err
 		}
+		resp := api.PortListResponse{Ports: ports}
 
 		if flagJsonOutput {
 			b, _ := json.MarshalIndent(resp, "", "  ")
@@ -424,51 +434,63 @@ var listVmCmd = &cobra.Command{
 	Use:     "vms",
 	Aliases: []string{"vm", "instances", "servers"},
 	Short:   "List virtual machines",
-	Long:    "Fetches and displays a list of virtual machines in the project (determined by auth).",
+	Long: `Fetches and displays a list of virtual machines in the project (determined by auth).
+
+Filters are applied by Nova, so only matching VMs are fetched. With
+--changes-since, Nova also returns VMs deleted since that time.
+Use --columns to add flavor, created, host or project columns.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		computeURL, err := validateTokenEndpoint(tok, "compute")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "compute"); err != nil {
 			return err
 		}
 
-		queryParams := make(map[string]string)
-		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
-			queryParams["limit"] = fmt.Sprintf("%d", limit)
+		columns, err := vmColumns(cmd)
+		if err != nil {
+			return err
 		}
-		if marker, _ := cmd.Flags().GetString("marker"); marker != "" {
-			queryParams["marker"] = marker
+
+		queryParams, err := vmQueryParams(cmd)
+		if err != nil {
+			return err
 		}
 
-		resp, err := api.ListVMsDetail(computeURL, tok.Value, queryParams)
+		servers, err := listPages(cmd, api.NewClient(tok).Compute().ServerDetailPager(queryParams, flagPageSize))
 		if err != nil {
 			return err
 		}
 
-		nameFilter, _ := cmd.Flags().GetString("name")
 		var vmList []responseparser.VM
-		for _, v := range resp.Servers {
-			if nameFilter == "" || strings.Contains(
-				strings.ToLower(v.Name),
-				strings.ToLower(nameFilter),
-			) {
-				// Extract IPs from addresses map (populated by /servers/detail)
-				var ips []string
-				for _, addrs := range v.Addresses {
-					for _, addr := range addrs {
-						ips = append(ips, addr.Addr)
-					}
+		for _, v := range servers {
+			// Extract IPs from addresses map (populated by /servers/detail)
+			var ips []string
+			for _, addrs := range v.Addresses {
+				for _, addr := range addrs {
+					ips = append(ips, addr.Addr)
 				}
+			}
 
-				// Get power state string
-				powerState := getPowerStateString(v.PowerState)
-
-				vmList = append(vmList, responseparser.VM{
-					ID:         v.ID,
-					Name:       v.Name,
-					PowerState: powerState,
-					IPs:        strings.Join(ips, ", "),
-				})
+			vm := responseparser.VM{
+				ID:         v.ID,
+				Name:       v.Name,
+				PowerState: getPowerStateString(v.PowerState),
+				IPs:        strings.Join(ips, ", "),
 			}
+			for _, c := range columns {
+				switch c {
+				case "flavor":
+					vm.Flavor = v.Flavor.OriginalName
+					if vm.Flavor == "" {
+						vm.Flavor = v.Flavor.ID
+					}
+				case "created":
+					vm.Created = v.Created
+				case "host":
+					vm.Host = v.Host
+				case "project":
+					vm.Project = v.TenantID
+				}
+			}
+			vmList = append(vmList, vm)
 		}
 
 		if flagJsonOutput {
@@ -480,26 +502,109 @@ var listVmCmd = &cobra.Command{
 		sort.Slice(vmList, func(i, j int) bool {
 			return natsort.Compare(vmList[i].Name, vmList[j].Name)
 		})
-		responseparser.PrintVMsTable(vmList)
+		responseparser.PrintVMsTable(vmList, columns...)
 		return nil
 	},
 }
 
+// vmQueryParams builds the Nova filters for 'list vms' from its flags.
+func vmQueryParams(cmd *cobra.Command) (map[string]string, error) {
+	queryParams := make(map[string]string)
+	if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
+		queryParams["limit"] = fmt.Sprintf("%d", limit)
+	}
+	if marker, _ := cmd.Flags().GetString("marker"); marker != "" {
+		queryParams["marker"] = marker
+	}
+
+	// Passed through as given; Nova treats name and ip as regular expressions
+	for flag, param := range map[string]string{"name": "name", "ip": "ip", "compute-host": "host"} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			queryParams[param] = v
+		}
+	}
+	if status, _ := cmd.Flags().GetString("status"); status != "" {
+		queryParams["status"] = strings.ToUpper(status)
+	}
+	if all, _ := cmd.Flags().GetBool("all-tenants"); all {
+		queryParams["all_tenants"] = "1"
+	}
+
+	if flavor, _ := cmd.Flags().GetString("flavor"); flavor != "" {
+		id, err := resolveID(cmd, resolver.KindFlavor, flavor)
+		if err != nil {
+			return nil, err
+		}
+		queryParams["flavor"] = id
+	}
+	if image, _ := cmd.Flags().GetString("image"); image != "" {
+		id, err := resolveID(cmd, resolver.KindImage, image)
+		if err != nil {
+			return nil, err
+		}
+		queryParams["image"] = id
+	}
+
+	if since, _ := cmd.Flags().GetString("changes-since"); since != "" {
+		ts, err := parseChangesSince(since, time.Now())
+		if err != nil {
+			return nil, usageError{err}
+		}
+		queryParams["changes-since"] = ts
+	}
+
+	// Tag filters are applied by Nova; each takes a comma-separated list
+	for flag, param := range map[string]string{"tag": "tags", "tag-any": "tags-any", "not-tags": "not-tags"} {
+		if tags, _ := cmd.Flags().GetStringSlice(flag); len(tags) > 0 {
+			queryParams[param] = strings.Join(tags, ",")
+		}
+	}
+	return queryParams, nil
+}
+
+// parseChangesSince accepts a timestamp (RFC 3339 or a plain date) or a
+// duration back from now, e.g. 24h, and returns it in the form Nova expects.
+func parseChangesSince(s string, now time.Time) (string, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d).UTC().Format(time.RFC3339), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("invalid --changes-since %q: use a timestamp like 2024-05-01T12:00:00Z, a date, or a duration like 24h", s)
+}
+
+// vmColumns returns the optional columns requested with --columns.
+func vmColumns(cmd *cobra.Command) ([]string, error) {
+	requested, _ := cmd.Flags().GetStringSlice("columns")
+	var columns []string
+	for _, c := range requested {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(responseparser.VMColumns, c) {
+			return nil, usageError{fmt.Errorf("unknown column %q; choose from %s", c, strings.Join(responseparser.VMColumns, ", "))}
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
 var listVolumesCmd = &cobra.Command{
 	Use:     "volumes",
 	Aliases: []string{"vol", "vols", "storage", "volume"},
 	Short:   "List storage volumes",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		storageURL, err := validateTokenEndpoint(tok, "volumev3")
-		if err != nil {
+		if _, err := validateTokenEndpoint(tok, "volumev3"); err != nil {
 			return err
 		}
 
 		queryParams := make(map[string]string)
-		resp, err := api.ListVolumes(storageURL, tok.Value, queryParams)
+		volumes, err := listPages(cmd, api.NewClient(tok).Volume().VolumePager(queryParams, flagPageSize))
 		if err != nil {
 			return err
 		}
+		resp := api.VolumeListResponse{Volumes: volumes}
 
 		if flagJsonOutput {
 			b, _ := json.MarshalIndent(resp.Volumes, "", "  ")
@@ -524,9 +629,40 @@ var listVolumesCmd = &cobra.Command{
 	},
 }
 
+var (
+	flagListAll  bool
+	flagPageSize int
+)
+
+// listPages fetches the first page from pager, or every page with --all.
+// When more results are left it says so on stderr, keeping --json clean.
+func listPages[T any](cmd *cobra.Command, pager *api.Pager[T]) ([]T, error) {
+	if flagListAll {
+		return pager.All(cmd.Context())
+	}
+	items, err := pager.NextPage(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	if pager.More() {
+		fmt.Fprintf(os.Stderr, "Showing the first %d results; use --all to fetch every page\n", len(items))
+	}
+	return items, nil
+}
+
+// addPaginationFlags adds --all and --page-size to a list command.
+func addPaginationFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagListAll, "all", false, "Fetch every page of results")
+	cmd.Flags().IntVar(&flagPageSize, "page-size", 0, "Results per request (default: the server's page size)")
+}
+
 func init() {
 	listCmd.PersistentFlags().BoolVar(&flagJsonOutput, "json", false, "Output in JSON format")
 
+	for _, c := range []*cobra.Command{listFlavorsCmd, listImagesCmd, listNetworksCmd, listPortsCmd, listVmCmd, listVolumesCmd} {
+		addPaginationFlags(c)
+	}
+
 	listImagesCmd.Flags().String("name", "", "Filter by image name")
 	listImagesCmd.Flags().String("visibility", "", "Filter by visibility (public, private, etc.)")
 	listImagesCmd.Flags().String("status", "", "Filter by image status")
@@ -542,10 +678,20 @@ func init() {
 	listPortsCmd.Flags().String("mac-address", "", "Filter ports by MAC address")
 	listPortsCmd.Flags().String("status", "", "Filter ports by status")
 
-	listVmCmd.Flags().String("name", "", "Filter by VM name")
-	listVmCmd.Flags().String("status", "", "Filter by VM status")
+	listVmCmd.Flags().String("name", "", "Filter by VM name (regular expression)")
+	listVmCmd.Flags().String("status", "", "Filter by VM status (e.g. ACTIVE, SHUTOFF, ERROR)")
+	listVmCmd.Flags().String("flavor", "", "Filter by flavor name or ID")
+	listVmCmd.Flags().String("image", "", "Filter by image name or ID")
+	listVmCmd.Flags().String("compute-host", "", "Filter by compute host (admin only)")
+	listVmCmd.Flags().String("ip", "", "Filter by IPv4 address (regular expression)")
+	listVmCmd.Flags().String("changes-since", "", "Only VMs changed since a time (RFC 3339 or date) or duration ago (e.g. 24h)")
+	listVmCmd.Flags().Bool("all-tenants", false, "List VMs in all projects (admin only)")
+	listVmCmd.Flags().StringSlice("columns", nil, "Extra columns to show: "+strings.Join(responseparser.VMColumns, ", "))
 	listVmCmd.Flags().Int("limit", 0, "Limit the number of VMs returned")
 	listVmCmd.Flags().String("marker", "", "Marker for pagination")
+	listVmCmd.Flags().StringSlice("tag", nil, "Only VMs with all of these tags (repeatable or comma-separated)")
+	listVmCmd.Flags().StringSlice("tag-any", nil, "Only VMs with at least one of these tags")
+	listVmCmd.Flags().StringSlice("not-tags", nil, "Exclude VMs that have all of these tags")
 
 	listFlavorsCmd.Flags().String("project-id", "", "Project ID")
 	listFlavorsCmd.Flags().String("sort-key", "", "Sort key for flavors")
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -28,6 +30,14 @@ import (
 // flag to shut down the VM after migration.
 
 // 'migrate' parent command
+// Glance rejects deleting an image with 409 for a while after a volume has
+// been created from it, so requests for the temporary images get a longer
+// retry budget than the configured one (3s, 6s, 12s, 24s between attempts).
+const (
+	tempImageRetries    = 5
+	tempImageRetryDelay = 3 * time.Second
+)
+
 var migrateCmd = &cobra.Command{
 	Use:     "migrate",
 	Aliases: []string{"mig"},
@@ -48,31 +58,6 @@ var migrateVMCmd = &cobra.Command{
     --size 20 \
     --shutdown`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Preemptive reauth to ensure token doesn't expire during migration
-		user := viper.GetString("username")
-		pass := viper.GetString("password")
-		domain := viper.GetString("domain")
-		project := viper.GetString("project")
-
-		if user != "" && pass != "" && domain != "" && project != "" {
-			host := viper.GetString("host")
-			if host == "" {
-				hostFlag, _ := cmd.Flags().GetString("host")
-				host = hostFlag
-			}
-			if host != "" {
-				// Force reauth to get a fresh token
-				_, err := api.Authenticate(host, domain, project, user, pass, true)
-				if err != nil {
-					return fmt.Errorf("preemptive reauth failed: %v", err)
-				}
-				tok, err = api.LoadTokenStruct(host)
-				if err != nil {
-					return fmt.Errorf("failed to load token after reauth: %v", err)
-				}
-			}
-		}
-
 		computeURL, err := validateTokenEndpoint(tok, "compute")
 		if err != nil {
 			return err
@@ -126,9 +111,9 @@ var migrateVMCmd = &cobra.Command{
 			}
 		}
 
-		fid, err := api.GetFlavorIDByName(computeURL, tok.Value, flavorRef)
-		if err == nil && fid != "" {
-			flavorRef = fid
+		flavorRef, err = resolveID(cmd, resolver.KindFlavor, flavorRef)
+		if err != nil {
+			return err
 		}
 
 		// --- BEGIN SKETCHY STUFF ---
@@ -190,22 +175,20 @@ var migrateVMCmd = &cobra.Command{
 			Visibility:   "shared",
 		}
 
-		imageID, err := api.CreateAndUploadImage(imageURL, tok.Value, imgReq, file)
+		imageID, err := api.CreateAndUploadImage(cmd.Context(), imageURL, tok.Value, imgReq, file)
 		if err != nil {
-			return fmt.Errorf("failed to create/upload image: %v", err)
+			return fmt.Errorf("failed to create/upload image: %w", err)
 		}
+		releaseImage := cleanupImage(imageID)
 
-		var imageSize int64
-		for i := 0; i < 3; i++ {
-			imageSize, err = api.GetImageSize(imageURL, tok.Value, imageID)
-			if err == nil {
-				break
-			}
-			time.Sleep(5 * time.Second)
-		}
+		images := api.NewClient(tok).Image()
+		imageCtx := httpclient.WithRetries(cmd.Context(), tempImageRetries, tempImageRetryDelay)
+
+		image, err := images.GetImage(imageCtx, imageID)
 		if err != nil {
-			return fmt.Errorf("failed to get image size after retries: %v", err)
+			return fmt.Errorf("failed to get image size: %v", err)
 		}
+		imageSize := image.Size
 
 		imageSizeGB := int64(0)
 		if migrateFlagVMSize == 0 {
@@ -237,6 +220,7 @@ var migrateVMCmd = &cobra.Command{
 
 		// Handle secondary disk if specified
 		var secondaryVolumeID string
+		releaseSecondaryVolume := func() {}
 		if migrateFlagSecondaryVMDK != "" {
 			storageURL, err := validateTokenEndpoint(tok, "volumev3")
 			if err != nil {
@@ -265,10 +249,11 @@ var migrateVMCmd = &cobra.Command{
 				Visibility:   "shared",
 			}
 
-			secondaryImageID, err := api.CreateAndUploadImage(imageURL, tok.Value, imgReq, file)
+			secondaryImageID, err := api.CreateAndUploadImage(cmd.Context(), imageURL, tok.Value, imgReq, file)
 			if err != nil {
-				return fmt.Errorf("failed to create/upload secondary image: %v", err)
+				return fmt.Errorf("failed to create/upload secondary image: %w", err)
 			}
+			releaseSecondaryImage := cleanupImage(secondaryImageID)
 
 			fmt.Printf("Creating volume from secondary image...\n")
 			// Round up the secondary volume size to the next whole GB so the volume is never
@@ -280,24 +265,57 @@ var migrateVMCmd = &cobra.Command{
 			}
 
 			secondaryVolumeID = volumeResp.Volume.ID
+			releaseSecondaryVolume = cleanupVolume(secondaryVolumeID)
 
 			fmt.Printf("Waiting for secondary volume to become available...\n")
-			err = api.WaitForVolumeStatus(storageURL, tok.Value, secondaryVolumeID, "available")
+			err = api.WaitForVolumeStatus(cmd.Context(), storageURL, tok.Value, secondaryVolumeID, "available", waitOptions("Volume"))
 			if err != nil {
 				return fmt.Errorf("failed waiting for secondary volume to become available: %v", err)
 			}
 
 			fmt.Printf("Deleting temporary secondary image %s...\n", secondaryImageID)
-			for i := 0; i < 3; i++ {
-				err = api.DeleteImage(imageURL, tok.Value, secondaryImageID)
-				if err == nil {
-					break
-				}
-				time.Sleep(5 * time.Second)
+			err = images.DeleteImage(imageCtx, secondaryImageID)
+			if err != nil {
+				return fmt.Errorf("failed to delete temporary secondary image: %v", err)
 			}
+			releaseSecondaryImage()
+		}
+
+		// Create the ports before the VM so that, if a later step fails, the
+		// VM is cleaned up first (cleanups run newest first) and the volume
+		// and ports are deleted once it has released them.
+		netInfo := make([]map[string]interface{}, 0)
+		var portIDs []string
+		var releasePorts []func()
+		for i, netNameOrID := range networkIDs {
+			netNameOrID = strings.TrimSpace(netNameOrID)
+			macAddr := strings.TrimSpace(macAddresses[i])
+
+			// If the user specified "auto", then omit the mac_addr field by setting it to empty.
+			if strings.ToLower(macAddr) == "auto" {
+				macAddr = ""
+			}
+
+			netNameOrID, err = resolveID(cmd, resolver.KindNetwork, netNameOrID)
 			if err != nil {
-				return fmt.Errorf("failed to delete temporary secondary image after retries: %v", err)
+				return err
+			}
+
+			fmt.Printf("Creating port on network '%s' with MAC '%s'...\n", netNameOrID, macAddr)
+
+			// Create a port, using the MAC address for unmanaged networks
+			portResp, err := api.CreatePort(networkURL, tok.Value, netNameOrID, macAddr, "", nil, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create port on network %s: %v", netNameOrID, err)
 			}
+			portIDs = append(portIDs, portResp.Port.ID)
+			releasePorts = append(releasePorts, cleanupPort(portResp.Port.ID))
+
+			// Optionally, add the network info to your summary.
+			netInfo = append(netInfo, map[string]interface{}{
+				"network_id":  netNameOrID,
+				"mac_address": portResp.Port.MACAddress,
+			})
 		}
 
 		vmReq := api.CreateVMRequest{}
@@ -326,9 +344,12 @@ var migrateVMCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to create VM: %v", err)
 		}
+		// Until its disks and networks are attached the VM is half-made;
+		// deleting it also deletes the root volume built from the image.
+		releaseVM := cleanupVM(vmResp.Server.ID)
 
 		// Wait for ACTIVE
-		vmDetails, err := api.WaitForStatus(computeURL, tok.Value, vmResp.Server.ID, "ACTIVE")
+		vmDetails, err := api.WaitForStatus(cmd.Context(), computeURL, tok.Value, vmResp.Server.ID, waitOptions("VM"), "ACTIVE")
 		if err != nil {
 			return fmt.Errorf("failed waiting for VM to become ACTIVE: %v", err)
 		}
@@ -342,67 +363,44 @@ var migrateVMCmd = &cobra.Command{
 			}
 		}
 
-		netInfo := make([]map[string]interface{}, 0)
-		for i, netNameOrID := range networkIDs {
-			netNameOrID = strings.TrimSpace(netNameOrID)
-			macAddr := strings.TrimSpace(macAddresses[i])
-
-			// If the user specified "auto", then omit the mac_addr field by setting it to empty.
-			if strings.ToLower(macAddr) == "auto" {
-				macAddr = ""
-			}
-
-			// Try to resolve network name->ID
-			netID, err := api.GetNetworkIDByName(networkURL, tok.Value, netNameOrID)
-			if err == nil && netID != "" {
-				netNameOrID = netID
-			}
-
-			fmt.Printf("Attaching network '%s' to VM '%s' with MAC '%s'...\n",
-				netNameOrID, vmDetails.ID, macAddr)
-
-			// Create a port, using the MAC address for unmanaged networks
-			portResp, err := api.CreatePort(networkURL, tok.Value, netNameOrID, macAddr, "", nil, nil)
+		for _, portID := range portIDs {
+			fmt.Printf("Attaching port '%s' to VM '%s'...\n", portID, vmDetails.ID)
+			_, err = api.AttachNetworkToVM(networkURL, computeURL, tok.Value, vmDetails.ID, "", portID, nil)
 			if err != nil {
-				return fmt.Errorf("failed to create port on network %s: %v", netNameOrID, err)
-			}
-
-			// Attach the port to the VM (unchanged)
-			_, err = api.AttachNetworkToVM(networkURL, computeURL, tok.Value, vmDetails.ID, "", portResp.Port.ID, nil)
-			if err != nil {
-				return fmt.Errorf("failed to attach port '%s' to VM '%s': %v", portResp.Port.ID, vmDetails.ID, err)
+				return fmt.Errorf("failed to attach port '%s' to VM '%s': %v", portID, vmDetails.ID, err)
 			}
+		}
 
-			// Optionally, add the network info to your summary.
-			netInfo = append(netInfo, map[string]interface{}{
-				"network_id":  netNameOrID,
-				"mac_address": portResp.Port.MACAddress,
-			})
+		// The VM now owns its volumes and ports
+		releaseVM()
+		releaseSecondaryVolume()
+		for _, release := range releasePorts {
+			release()
 		}
 
-		api.RebootVM(computeURL, tok.Value, vmDetails.ID, "HARD")
+		// With --no-wait the reboot still has to finish before a shutdown
+		// can be requested, or Nova rejects the stop as a conflict.
+		rebootOpts := waitOptions("VM")
+		rebootOpts.NoWait = flagNoWait && !migrateFlagShutdown
+		api.RebootVM(cmd.Context(), computeURL, tok.Value, vmDetails.ID, "HARD", rebootOpts)
 
 		// -- Not very reliable if the VM is hung since
 		// this only sends a soft os-stop signal, it takes
 		// ~5 minutes if acpid is not running in the VM.
 		if migrateFlagShutdown {
 			fmt.Printf("Shutting down VM '%s'...\n", vmDetails.ID)
-			if err := api.StopVM(computeURL, tok.Value, vmDetails.ID); err != nil {
+			if err := api.StopVM(cmd.Context(), computeURL, tok.Value, vmDetails.ID, waitOptions("VM")); err != nil {
 				return fmt.Errorf("failed to shut down VM: %v", err)
 			}
 		}
 
 		fmt.Printf("Deleting temporary image %s...\n", imageID)
-		for i := 0; i < 3; i++ {
-			err = api.DeleteImage(imageURL, tok.Value, imageID)
-			if err == nil {
-				break
-			}
-			time.Sleep(5 * time.Second)
-		}
-		if err != nil {
-			return fmt.Errorf("failed to delete temporary image after retries: %v", err)
+		// The VM no longer needs the image, so failing to delete it
+		// shouldn't fail the migration
+		if err := images.DeleteImage(imageCtx, imageID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to delete temporary image %s: %v\n", imageID, err)
 		}
+		releaseImage()
 
 		summary := map[string]interface{}{
 			"vm_id":   vmDetails.ID,
@@ -496,6 +494,7 @@ func init() {
 	migrateVMCmd.Flags().BoolVar(&migrateFlagI440fx, "i440fx", false, "Set i440fx machine type for the image (legacy BIOS)")
 	migrateVMCmd.Flags().StringVar(&migrateFlagSecondaryVMDK, "secondary-vmdk", "", "Local path to secondary VMDK file to attach as additional volume")
 	migrateVMCmd.Flags().BoolVar(&migrateFlagUEFI, "uefi", false, "Set UEFI firmware and q35 machine type for the image (mutually exclusive with --i440fx)")
+	addWaitFlags(migrateVMCmd, true)
 	migrateFindCmd.Flags().BoolVar(&migrateFindVMDKSingle, "single", false, "Find a single VMDK file")
 
 	migrateCmd.AddCommand(migrateVMCmd)
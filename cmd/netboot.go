@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
@@ -29,9 +30,9 @@ var setNetworkInstallCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
 		enabled := value == "true"
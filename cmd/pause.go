@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jessegalley/vhicmd/api"
@@ -8,50 +9,56 @@ import (
 )
 
 var pauseCmd = &cobra.Command{
-	Use:   "pause",
-	Short: "Pause a VM",
+	Use:   "pause [vm]...",
+	Short: "Pause VMs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
-			return fmt.Errorf("requires exactly one argument (VM name or ID)")
-		}
-
 		computeURL, err := validateTokenEndpoint(tok, "compute")
 		if err != nil {
 			return err
 		}
 
-		vmID, err := api.GetVMIDByName(computeURL, tok.Value, args[0])
+		targets, bulk, err := selectVMs(cmd, args)
 		if err != nil {
 			return err
 		}
 
-		return api.PauseVM(computeURL, tok.Value, vmID)
+		return runOnVMs(cmd, "pause", targets, bulk, func(ctx context.Context, t vmTarget) error {
+			if err := api.PauseVM(computeURL, tok.Value, t.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Paused VM %s\n", t.ID)
+			return nil
+		})
 	},
 }
 
 var unpauseCmd = &cobra.Command{
-	Use:   "unpause",
-	Short: "Unpause a VM",
+	Use:   "unpause [vm]...",
+	Short: "Unpause VMs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
-			return fmt.Errorf("requires exactly one argument (VM name or ID)")
-		}
-
 		computeURL, err := validateTokenEndpoint(tok, "compute")
 		if err != nil {
 			return err
 		}
 
-		vmID, err := api.GetVMIDByName(computeURL, tok.Value, args[0])
+		targets, bulk, err := selectVMs(cmd, args)
 		if err != nil {
 			return err
 		}
 
-		return api.UnpauseVM(computeURL, tok.Value, vmID)
+		return runOnVMs(cmd, "unpause", targets, bulk, func(ctx context.Context, t vmTarget) error {
+			if err := api.UnpauseVM(computeURL, tok.Value, t.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Unpaused VM %s\n", t.ID)
+			return nil
+		})
 	},
 }
 
 func init() {
+	addBulkFlags(pauseCmd)
+	addBulkFlags(unpauseCmd)
 	rootCmd.AddCommand(pauseCmd)
 	rootCmd.AddCommand(unpauseCmd)
 }
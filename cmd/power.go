@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRescueImage string
+	flagLockReason  string
+)
+
+var powerCmd = &cobra.Command{
+	Use:   "power",
+	Short: "Change the power state of VMs",
+	Long: `Start, stop, suspend, shelve, rescue or lock one or more VMs.
+
+Each command takes VM names or IDs, or picks VMs with --selector, --metadata
+or --from-file, and, unless --no-wait is given, waits for every VM to reach
+the resulting state. Use --parallel to work on several VMs at once.`,
+}
+
+// powerAction describes one 'power' subcommand.
+type powerAction struct {
+	use   string
+	short string
+	done  string // past tense for the result line, e.g. "Started"
+	wait  bool   // whether the action has a state to wait for
+	run   func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error
+}
+
+var powerActions = []powerAction{
+	{"start", "Start stopped VMs", "Started", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.StartVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"stop", "Stop (shut down) VMs", "Stopped", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.StopVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"suspend", "Suspend VMs to disk", "Suspended", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.SuspendVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"resume", "Resume suspended VMs", "Resumed", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.ResumeVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"shelve", "Shelve VMs, releasing their host resources", "Shelved", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.ShelveVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"unshelve", "Unshelve shelved VMs", "Unshelved", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.UnshelveVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"rescue", "Boot VMs into rescue mode", "Rescued", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		pass, err := api.RescueVM(ctx, computeURL, tok.Value, vmID, flagRescueImage, opts)
+		if err == nil && pass != "" {
+			fmt.Printf("Rescue password for VM %s: %s\n", vmID, pass)
+		}
+		return err
+	}},
+	{"unrescue", "Return rescued VMs to normal", "Unrescued", true, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.UnrescueVM(ctx, computeURL, tok.Value, vmID, opts)
+	}},
+	{"lock", "Lock VMs against changes by non-admin users", "Locked", false, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.LockVM(computeURL, tok.Value, vmID, flagLockReason)
+	}},
+	{"unlock", "Unlock locked VMs", "Unlocked", false, func(ctx context.Context, computeURL, vmID string, opts api.WaitOptions) error {
+		return api.UnlockVM(computeURL, tok.Value, vmID)
+	}},
+}
+
+// newPowerCmd builds the subcommand for a.
+func newPowerCmd(a powerAction) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   a.use + " [vm]...",
+		Short: a.short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			computeURL, err := validateTokenEndpoint(tok, "compute")
+			if err != nil {
+				return err
+			}
+
+			// Resolve every reference before touching any VM
+			targets, bulk, err := selectVMs(cmd, args)
+			if err != nil {
+				return err
+			}
+
+			return runOnVMs(cmd, a.use, targets, bulk, func(ctx context.Context, t vmTarget) error {
+				if err := a.run(ctx, computeURL, t.ID, waitOptions("VM "+t.ID)); err != nil {
+					return err
+				}
+				fmt.Printf("%s VM %s\n", a.done, t.ID)
+				return nil
+			})
+		},
+	}
+	addBulkFlags(cmd)
+	if a.wait {
+		addWaitFlags(cmd, true)
+	}
+	return cmd
+}
+
+func init() {
+	for _, a := range powerActions {
+		c := newPowerCmd(a)
+		switch a.use {
+		case "rescue":
+			c.Flags().StringVar(&flagRescueImage, "image", "", "Image to boot the rescue system from (default: the VM's own image)")
+			c.PreRunE = func(cmd *cobra.Command, args []string) error {
+				if flagRescueImage == "" {
+					return nil
+				}
+				id, err := resolveID(cmd, resolver.KindImage, flagRescueImage)
+				flagRescueImage = id
+				return err
+			}
+		case "lock":
+			c.Flags().StringVar(&flagLockReason, "reason", "", "Why the VM is locked (needs Nova microversion 2.73)")
+		}
+		powerCmd.AddCommand(c)
+	}
+	rootCmd.AddCommand(powerCmd)
+}
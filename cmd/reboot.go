@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jessegalley/vhicmd/api"
 	"github.com/spf13/cobra"
@@ -13,50 +15,53 @@ var rebootCmd = &cobra.Command{
 }
 
 var hardRebootCmd = &cobra.Command{
-	Use:   "hard <vm-id>",
-	Short: "Perform a hard reboot on a VM",
-	Args:  cobra.ExactArgs(1),
+	Use:   "hard [vm-id]...",
+	Short: "Perform a hard reboot on VMs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		vmID := args[0]
-
-		computeURL, err := validateTokenEndpoint(tok, "compute")
-		if err != nil {
-			return err
-		}
-
-		err = api.RebootVM(computeURL, tok.Value, vmID, "HARD")
-		if err != nil {
-			return err
-		}
-
-		fmt.Printf("Hard reboot initiated for VM %s\n", vmID)
-		return nil
+		return rebootVMs(cmd, args, "HARD")
 	},
 }
 
 var softRebootCmd = &cobra.Command{
-	Use:   "soft <vm-id>",
-	Short: "Perform a soft reboot on a VM",
-	Args:  cobra.ExactArgs(1),
+	Use:   "soft [vm-id]...",
+	Short: "Perform a soft reboot on VMs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		vmID := args[0]
+		return rebootVMs(cmd, args, "SOFT")
+	},
+}
 
-		computeURL, err := validateTokenEndpoint(tok, "compute")
-		if err != nil {
-			return err
-		}
+// rebootVMs reboots the selected VMs and, unless --no-wait is given, waits
+// for each to be ACTIVE again.
+func rebootVMs(cmd *cobra.Command, args []string, rebootType string) error {
+	computeURL, err := validateTokenEndpoint(tok, "compute")
+	if err != nil {
+		return err
+	}
 
-		err = api.RebootVM(computeURL, tok.Value, vmID, "SOFT")
-		if err != nil {
+	targets, bulk, err := selectVMs(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	kind := strings.ToUpper(rebootType[:1]) + strings.ToLower(rebootType[1:])
+	return runOnVMs(cmd, "reboot", targets, bulk, func(ctx context.Context, t vmTarget) error {
+		if err := api.RebootVM(ctx, computeURL, tok.Value, t.ID, rebootType, waitOptions("VM "+t.ID)); err != nil {
 			return err
 		}
-
-		fmt.Printf("Soft reboot initiated for VM %s\n", vmID)
+		if flagNoWait {
+			fmt.Printf("%s reboot initiated for VM %s\n", kind, t.ID)
+		} else {
+			fmt.Printf("%s rebooted VM %s\n", kind, t.ID)
+		}
 		return nil
-	},
+	})
 }
 
 func init() {
+	addBulkFlags(hardRebootCmd)
+	addBulkFlags(softRebootCmd)
+	addWaitFlags(hardRebootCmd, true)
+	addWaitFlags(softRebootCmd, true)
 	rebootCmd.AddCommand(hardRebootCmd)
 	rebootCmd.AddCommand(softRebootCmd)
 	rootCmd.AddCommand(rebootCmd)
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/spf13/cobra"
+)
+
+var flagRebuildImage string
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild <vm>",
+	Short: "Reinstall a VM from an image, keeping its ports and IPs",
+	Long: `Reinstall a VM from an image. The VM keeps its ID, name, flavor, ports,
+MAC addresses and IPs; its boot disk is replaced with a fresh copy of the
+image. User data works as for 'create vm', including templates with
+--ci-data or --ci-data-file; without --user-data the VM keeps its old user
+data.
+
+The VM's ports are compared before and after, and the command fails if any
+of them changed. Volume-backed VMs, which is what 'create vm' makes, need a
+cloud with Nova microversion 2.93 or newer.
+
+Example:
+  vhicmd rebuild test-01 --image ubuntu-24.04
+  vhicmd rebuild test-01 --image ubuntu-24.04 --user-data init.yaml --ci-data 'hostname:test-01'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
+		}
+		if _, err := validateTokenEndpoint(tok, "network"); err != nil {
+			return err
+		}
+		if err := checkUserDataFlags(); err != nil {
+			return err
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+		imageID, err := resolveID(cmd, resolver.KindImage, flagRebuildImage)
+		if err != nil {
+			return err
+		}
+
+		req := api.RebuildVMRequest{ImageRef: imageID}
+		if flagUserData != "" {
+			if req.UserData, err = userDataFromFlags(); err != nil {
+				return err
+			}
+		}
+
+		before, err := vmPortState(cmd.Context(), vmID)
+		if err != nil {
+			return err
+		}
+
+		if err := confirm(fmt.Sprintf("Rebuild VM %s from image %s? Its current disk contents will be lost. [y/N]: ", vmID, flagRebuildImage)); err != nil {
+			return err
+		}
+		if err := api.RebuildVM(cmd.Context(), computeURL, tok.Value, vmID, req, waitOptions("VM")); err != nil {
+			return err
+		}
+		if flagNoWait {
+			fmt.Printf("Rebuild of VM %s started; ports are not checked with --no-wait\n", vmID)
+			return nil
+		}
+
+		after, err := vmPortState(cmd.Context(), vmID)
+		if err != nil {
+			return fmt.Errorf("VM %s was rebuilt, but its ports could not be checked: %v", vmID, err)
+		}
+		if changes := portStateChanges(before, after); len(changes) > 0 {
+			return fmt.Errorf("VM %s was rebuilt, but its ports changed: %s", vmID, strings.Join(changes, "; "))
+		}
+
+		fmt.Printf("VM %s rebuilt from image %s; %d ports unchanged\n", vmID, flagRebuildImage, len(after))
+		return nil
+	},
+}
+
+// vmPortState maps the ID of each port on a VM to its MAC address and fixed
+// IPs.
+func vmPortState(ctx context.Context, vmID string) (map[string]string, error) {
+	ports, err := api.NewClient(tok).Network().ListPorts(ctx, map[string]string{"device_id": vmID})
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]string, len(ports.Ports))
+	for _, p := range ports.Ports {
+		var ips []string
+		for _, ip := range p.FixedIPs {
+			ips = append(ips, ip.IPAddress)
+		}
+		sort.Strings(ips)
+		state[p.ID] = fmt.Sprintf("%s %s", p.MACAddress, strings.Join(ips, ","))
+	}
+	return state, nil
+}
+
+// portStateChanges describes how the ports in after differ from before.
+func portStateChanges(before, after map[string]string) []string {
+	var changes []string
+	for id, was := range before {
+		now, ok := after[id]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("port %s (%s) is gone", id, was))
+		case now != was:
+			changes = append(changes, fmt.Sprintf("port %s changed from %s to %s", id, was, now))
+		}
+	}
+	for id, now := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, fmt.Sprintf("port %s (%s) is new", id, now))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func init() {
+	rebuildCmd.Flags().StringVar(&flagRebuildImage, "image", "", "Image to rebuild the VM from")
+	rebuildCmd.Flags().StringVar(&flagUserData, "user-data", "", "User script, bash, YAML (file path), use with --ci-data for templating, eg. {{%variable%}}")
+	rebuildCmd.Flags().StringVar(&flagCIData, "ci-data", "", "Template variables for cloud-init in format key:value,key:value")
+	rebuildCmd.Flags().StringVar(&flagCIDataFile, "ci-data-file", "", "File containing template variables (one key:value per line, supports quoted multi-line values)")
+	rebuildCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Don't ask for confirmation")
+	addWaitFlags(rebuildCmd, true)
+
+	rebuildCmd.MarkFlagRequired("image")
+	rootCmd.AddCommand(rebuildCmd)
+}
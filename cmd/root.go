@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/gookit/color"
 	"github.com/jessegalley/vhicmd/api"
 	"github.com/jessegalley/vhicmd/internal/config"
+	"github.com/jessegalley/vhicmd/internal/httpclient"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -15,24 +19,58 @@ import (
 // rootCmd represents the base command when called without any subcommands
 var (
 	rootCmd = &cobra.Command{
-		Use:          "vhicmd",
-		Short:        "A command line utility for calling the VHI compute API",
-		Long:         ``,
-		SilenceUsage: true,
+		Use:           "vhicmd",
+		Short:         "A command line utility for calling the VHI compute API",
+		Long:          ``,
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
 
-	cfgFile   string
-	rcDirFlag string
-	tok       api.Token
-	debugMode bool
+	cfgFile     string
+	rcDirFlag   string
+	flagProject string
+	flagContext string
+	tok         api.Token
+	debugMode   bool
+
+	flagRegion            string
+	flagEndpointInterface string
+
+	flagCACert   string
+	flagCert     string
+	flagKey      string
+	flagInsecure bool
+	flagProxy    string
+
+	flagTraceFile string
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Errors are printed here rather than by cobra so they can be colored and
+// mapped to an exit code per error class (see exitCode).
+//
+// The first SIGINT or SIGTERM cancels the command's context; temporary
+// resources the command registered with onCleanup are then removed before
+// exiting.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signalContext()
+	markUsageErrors(rootCmd)
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := context.Cause(ctx) == errInterrupted
+	stop()
+
+	if err != nil || interrupted {
+		runCleanups()
+	}
+	httpclient.CloseTrace()
+
+	if interrupted {
+		os.Exit(exitInterrupted)
+	}
 	if err != nil {
-		os.Exit(1)
+		printError(err)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -66,10 +104,20 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "Enable debug mode")
+	rootCmd.PersistentFlags().StringVar(&flagTraceFile, "trace-file", "", "Append a JSON-lines trace of API requests, secrets redacted, to this file ('-' for stderr)")
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	rootCmd.PersistentFlags().StringP("host", "H", "", "VHI host to connect to")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default is $HOME/.vhirc)")
 	rootCmd.PersistentFlags().StringVar(&rcDirFlag, "rc", "", "RC directory for config and token (overrides VHICMD_RCDIR)")
+	rootCmd.PersistentFlags().StringVar(&flagContext, "context", "", "Named context from the config to use (default is current_context)")
+	rootCmd.PersistentFlags().StringVar(&flagProject, "project", "", "Project to use; selects the matching cached token (default from config)")
+	rootCmd.PersistentFlags().StringVar(&flagRegion, "region", "", "Region whose endpoints to use (default from config, else the first in the catalog)")
+	rootCmd.PersistentFlags().StringVar(&flagCACert, "cacert", "", "PEM CA bundle to trust for VHI endpoints, in addition to the system roots")
+	rootCmd.PersistentFlags().StringVar(&flagCert, "cert", "", "Client certificate (PEM) for mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&flagKey, "key", "", "Client certificate key (PEM) for mutual TLS")
+	rootCmd.PersistentFlags().BoolVar(&flagInsecure, "insecure", false, "Skip TLS certificate verification (unsafe)")
+	rootCmd.PersistentFlags().StringVar(&flagProxy, "proxy", "", "Proxy for all API traffic: http://, https://, socks5:// or socks5h:// URL (default from config, else HTTPS_PROXY)")
+	rootCmd.PersistentFlags().StringVar(&flagEndpointInterface, "interface", "", "Endpoint interface to use: public, internal or admin (default from config, else public)")
 
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		if cmd.Name() == "version" ||
@@ -81,6 +129,34 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 		viper.Set("debug", debug)
 		debugMode = debug
 
+		if flagTraceFile != "" {
+			if err := httpclient.OpenTrace(flagTraceFile); err != nil {
+				return err
+			}
+		}
+
+		if err := applyActiveContext(); err != nil {
+			return err
+		}
+		if flagRegion != "" {
+			viper.Set("region", flagRegion)
+		}
+		if flagEndpointInterface != "" {
+			viper.Set("interface", flagEndpointInterface)
+		}
+
+		// Config commands only edit the config file
+		if cmd.Parent() != nil && cmd.Parent().Name() == "config" {
+			return nil
+		}
+
+		if err := configureTLS(); err != nil {
+			return err
+		}
+		if err := configureProxy(); err != nil {
+			return err
+		}
+
 		hostFlag, _ := cmd.Flags().GetString("host")
 		host := hostFlag
 		if host == "" {
@@ -91,42 +167,173 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 		}
 
 		// If this is the "auth" command, skip the token loading
-		if cmd.Name() == "auth" || (cmd.Parent() != nil && cmd.Parent().Name() == "config") {
+		if cmd.Name() == "auth" ||
+			(cmd.Parent() != nil && cmd.Parent().Name() == "auth" && (cmd.Name() == "list" || cmd.Name() == "revoke")) {
 			return nil
 		}
 
+		domain := viper.GetString("domain")
+		project := currentProject()
+
+		// Admin commands prefer a system- or domain-scoped token if one is cached
+		if cmd.Annotations[annotationTokenScope] == tokenScopeAdmin {
+			if t, err := adminToken(host, domain); err == nil {
+				tok = t
+				return selectEndpoints(&tok)
+			}
+		}
+
+		creds := configCredentials(host, domain, project)
+
 		var err error
-		tok, err = api.LoadTokenStruct(host)
+		tok, err = api.LoadTokenStruct(host, domain, project)
 		if err != nil {
-			if err.Error() == "token for "+host+" is expired" {
+			if errors.Is(err, api.ErrTokenExpired) {
 				// Try to reauth using saved credentials
-				user := viper.GetString("username")
-				pass := viper.GetString("password")
-				domain := viper.GetString("domain")
-				project := viper.GetString("project")
-
-				if user == "" || pass == "" || domain == "" || project == "" {
+				if !creds.CanReauth() {
 					return fmt.Errorf("the auth token for '%s' is expired; re-authenticate using 'vhicmd auth'", host)
 				}
 
-				_, err = doAuth(host, domain, project, user, pass)
+				_, err = creds.Reauthenticate()
 				if err != nil {
 					return fmt.Errorf("automatic reauth failed: %v", err)
 				}
 
 				// Reload token after successful reauth
-				tok, err = api.LoadTokenStruct(host)
+				tok, err = api.LoadTokenStruct(host, domain, project)
 				if err != nil {
 					return fmt.Errorf("failed to load token after reauth: %v", err)
 				}
-				fmt.Printf("Token expired; reauth successful for host '%s'\n", host)
+				fmt.Fprintf(os.Stderr, "Token expired; reauth successful for host '%s'\n", host)
+			} else if project != "" {
+				return fmt.Errorf("no valid auth token found on disk for host '%s', project '%s'; run 'vhicmd auth' first", host, project)
 			} else {
 				return fmt.Errorf("no valid auth token found on disk for host '%s'; run 'vhicmd auth' first", host)
 			}
 		}
 
+		if err := selectEndpoints(&tok); err != nil {
+			return err
+		}
+
+		// Tokens can still expire or be revoked mid-command (long uploads,
+		// bulk operations); let the API layer reauth and replay on 401
+		if creds.CanReauth() {
+			api.EnableTokenRefresh(creds)
+		}
+
+		return nil
+	}
+}
+
+// activeContext is the name of the context applied to the config, if any.
+var activeContext string
+
+// applyActiveContext overlays the context from --context, or current_context
+// in the config, onto the global viper settings.
+func applyActiveContext() error {
+	name := flagContext
+	if name == "" {
+		name = viper.GetString(config.CurrentContextKey)
+	}
+	if name == "" {
 		return nil
 	}
+
+	name = strings.ToLower(name)
+	if err := config.ApplyContext(viper.GetViper(), name); err != nil {
+		return err
+	}
+	activeContext = name
+	return nil
+}
+
+// Commands annotated with annotationTokenScope = tokenScopeAdmin run with a
+// system- or domain-scoped token when one is cached.
+const (
+	annotationTokenScope = "token_scope"
+	tokenScopeAdmin      = "admin"
+)
+
+// adminToken returns a cached system-scoped token for host, or failing that
+// a domain-scoped token for domain.
+func adminToken(host, domain string) (api.Token, error) {
+	if t, err := api.LoadScopedToken(host, "", api.ScopeSystem); err == nil {
+		return t, nil
+	}
+	return api.LoadScopedToken(host, domain, api.ScopeDomain)
+}
+
+// configureTLS applies the cacert, cert, key and insecure settings to all
+// HTTP requests. Flags override the config.
+func configureTLS() error {
+	if flagCACert != "" {
+		viper.Set("cacert", flagCACert)
+	}
+	if flagCert != "" {
+		viper.Set("cert", flagCert)
+	}
+	if flagKey != "" {
+		viper.Set("key", flagKey)
+	}
+	if flagInsecure {
+		viper.Set("insecure", true)
+	}
+
+	opts := httpclient.TLSOptions{
+		CACert:   viper.GetString("cacert"),
+		CertFile: viper.GetString("cert"),
+		KeyFile:  viper.GetString("key"),
+		Insecure: viper.GetBool("insecure"),
+	}
+	if err := httpclient.ConfigureTLS(opts); err != nil {
+		return fmt.Errorf("invalid TLS settings: %v", err)
+	}
+	if opts.Insecure {
+		fmt.Fprintln(os.Stderr, color.Style{color.FgRed, color.OpBold}.Render(
+			"WARNING: TLS certificate verification is DISABLED (insecure). Credentials and tokens sent to VHI can be intercepted."))
+	}
+	return nil
+}
+
+// configureProxy routes all HTTP requests through the proxy from --proxy or
+// 'proxy' in config (VHI_PROXY), falling back to HTTPS_PROXY and friends.
+func configureProxy() error {
+	if flagProxy != "" {
+		viper.Set("proxy", flagProxy)
+	}
+	return httpclient.ConfigureProxy(viper.GetString("proxy"))
+}
+
+// selectEndpoints points t.Endpoints at the configured region and interface.
+func selectEndpoints(t *api.Token) error {
+	return t.SelectEndpoints(viper.GetString("region"), strings.ToLower(viper.GetString("interface")))
+}
+
+// currentProject returns the project from --project, falling back to the config.
+func currentProject() string {
+	if flagProject != "" {
+		return flagProject
+	}
+	return viper.GetString("project")
+}
+
+// configCredentials collects the saved credentials for unattended reauth.
+func configCredentials(host, domain, project string) api.Credentials {
+	creds := api.Credentials{
+		Host:                host,
+		Method:              viper.GetString("auth_method"),
+		Domain:              domain,
+		Project:             project,
+		Username:            viper.GetString("username"),
+		Password:            plainConfigSecret("password"),
+		AppCredentialID:     viper.GetString("application_credential_id"),
+		AppCredentialSecret: plainConfigSecret("application_credential_secret"),
+	}
+	if hasDeferredSecret("password") || hasDeferredSecret("application_credential_secret") {
+		creds.Secret = configSecret
+	}
+	return creds
 }
 
 func initConfig() {
@@ -160,9 +367,13 @@ func initConfig() {
 	viper.Reset()
 	*viper.GetViper() = *v
 
+	if encryptionEnabled() {
+		api.TokenCipher = passphraseCipher{}
+	}
+
 	// Debug info if needed
 	if debugMode {
-		fmt.Printf("Config file: %s\n", v.ConfigFileUsed())
-		fmt.Printf("Token file: %s\n", api.TokenFile)
+		fmt.Fprintf(os.Stderr, "Config file: %s\n", v.ConfigFileUsed())
+		fmt.Fprintf(os.Stderr, "Token file: %s\n", api.TokenFile)
 	}
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/jessegalley/vhicmd/internal/secrets"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// secretBackendPassphrase encrypts secrets and the token file with a
+// passphrase, taken from VHICMD_PASSPHRASE or prompted for once per run.
+const secretBackendPassphrase = "passphrase"
+
+var cachedPassphrase string
+
+// encryptionEnabled reports whether secrets are stored encrypted.
+func encryptionEnabled() bool {
+	return viper.GetString("secret_backend") == secretBackendPassphrase
+}
+
+// getPassphrase returns the passphrase from VHICMD_PASSPHRASE, or prompts on
+// the terminal. With confirm set, the user must type it twice.
+func getPassphrase(confirm bool) (string, error) {
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+	if p := os.Getenv("VHICMD_PASSPHRASE"); p != "" {
+		cachedPassphrase = p
+		return p, nil
+	}
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("secrets are encrypted; set VHICMD_PASSPHRASE to decrypt them non-interactively")
+	}
+
+	fmt.Fprint(os.Stderr, "vhicmd passphrase: ")
+	p, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %v", err)
+	}
+	if len(p) == 0 {
+		return "", fmt.Errorf("empty passphrase")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "confirm passphrase: ")
+		again, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %v", err)
+		}
+		if string(again) != string(p) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	cachedPassphrase = string(p)
+	return cachedPassphrase, nil
+}
+
+// configSecret returns the plaintext of a secret config key: decrypted if it
+// is encrypted, or fetched from credential_helper if it is unset.
+func configSecret(key string) (string, error) {
+	value := viper.GetString(key)
+	if secrets.IsEncrypted(value) {
+		passphrase, err := getPassphrase(false)
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := secrets.Decrypt(value, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt '%s': %v", key, err)
+		}
+		return string(plaintext), nil
+	}
+	if value == "" {
+		if helper := viper.GetString("credential_helper"); helper != "" {
+			return secrets.FromHelper(helper, key)
+		}
+	}
+	return value, nil
+}
+
+// plainConfigSecret returns a secret config key only if it is stored in
+// plaintext, so callers can defer decryption until it is needed.
+func plainConfigSecret(key string) string {
+	value := viper.GetString(key)
+	if secrets.IsEncrypted(value) {
+		return ""
+	}
+	return value
+}
+
+// hasDeferredSecret reports whether key has to be decrypted or fetched from
+// a credential helper.
+func hasDeferredSecret(key string) bool {
+	value := viper.GetString(key)
+	return secrets.IsEncrypted(value) || (value == "" && viper.GetString("credential_helper") != "")
+}
+
+// sealConfigSecret encrypts value for writing to the config when the
+// passphrase backend is enabled.
+func sealConfigSecret(value string) (string, error) {
+	if !encryptionEnabled() || value == "" || secrets.IsEncrypted(value) {
+		return value, nil
+	}
+	passphrase, err := getPassphrase(false)
+	if err != nil {
+		return "", err
+	}
+	return secrets.Encrypt([]byte(value), passphrase)
+}
+
+// passphraseCipher encrypts the token file with the config passphrase.
+// Plaintext token files are read as-is so they are encrypted on next write.
+type passphraseCipher struct{}
+
+func (passphraseCipher) Seal(plaintext []byte) ([]byte, error) {
+	passphrase, err := getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := secrets.Encrypt(plaintext, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(enc + "\n"), nil
+}
+
+func (passphraseCipher) Open(data []byte) ([]byte, error) {
+	if !secrets.IsEncrypted(string(data)) {
+		return data, nil
+	}
+	passphrase, err := getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Decrypt(string(data), passphrase)
+}
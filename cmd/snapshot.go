@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
+	"github.com/jessegalley/vhicmd/internal/responseparser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagSnapshotName    string
+	flagSnapshotKeep    int
+	flagSnapshotDryRun  bool
+	flagRestoreAsNew    string
+	flagRestoreFlavor   string
+	flagRestoreNetworks string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snap"},
+	Short:   "Create, list, restore and prune VM snapshots",
+	Long: `Manage snapshots of a VM.
+
+A snapshot is a Nova image of the VM. For a volume-backed VM, which is what
+'create vm' makes, Nova also takes a Cinder snapshot of every attached volume
+and the image refers to them; the image itself holds no data. vhicmd treats
+the image and its volume snapshots as one set: pruning deletes both, and
+restoring brings back every volume.
+
+A VM that has been deleted can still be given by ID to list, restore or prune
+its snapshots.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <vm>",
+	Short: "Snapshot a VM",
+	Long: `Snapshot a VM and wait for the snapshot to be usable.
+
+Example:
+  vhicmd snapshot create web-01
+  vhicmd snapshot create web-01 --name web-01-before-upgrade
+  vhicmd snapshot create web-01 --keep 7    # then delete all but the newest 7`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
+		}
+		imageURL, err := validateTokenEndpoint(tok, "image")
+		if err != nil {
+			return err
+		}
+		storageURL, err := validateTokenEndpoint(tok, "volumev3")
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("keep") && flagSnapshotKeep < 1 {
+			return usageError{fmt.Errorf("--keep must be at least 1")}
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+		vm, err := api.GetVMDetails(computeURL, tok.Value, vmID)
+		if err != nil {
+			return err
+		}
+
+		name := flagSnapshotName
+		if name == "" {
+			name = fmt.Sprintf("%s-%s", vm.Name, time.Now().Format("20060102-150405"))
+		}
+
+		imageID, err := api.CreateVMSnapshot(computeURL, tok.Value, vmID, name, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot %s (%s) of VM %s started\n", name, imageID, vm.Name)
+
+		if flagNoWait {
+			fmt.Printf("Check it with 'vhicmd snapshot list %s'\n", vmID)
+		} else {
+			snap, err := api.WaitForVMSnapshot(cmd.Context(), imageURL, storageURL, tok.Value, imageID, waitOptions("Snapshot"))
+			if err != nil {
+				return err
+			}
+			if ids, _ := snap.VolumeSnapshotIDs(); len(ids) > 0 {
+				fmt.Printf("Snapshot %s created with %d volume snapshots\n", imageID, len(ids))
+			} else {
+				fmt.Printf("Snapshot %s created\n", imageID)
+			}
+		}
+
+		if cmd.Flags().Changed("keep") {
+			return pruneVMSnapshots(cmd, vmID, flagSnapshotKeep, false)
+		}
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list <vm>",
+	Aliases: []string{"ls"},
+	Short:   "List the snapshots of a VM, newest first",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := validateTokenEndpoint(tok, "image"); err != nil {
+			return err
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+		snaps, err := api.NewClient(tok).Image().ListVMSnapshots(cmd.Context(), vmID)
+		if err != nil {
+			return err
+		}
+		if len(snaps) == 0 {
+			fmt.Printf("VM %s has no snapshots\n", vmID)
+			return nil
+		}
+
+		responseparser.PrintVMSnapshotsTable(snapshotRows(snaps))
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <vm> [snapshot]",
+	Short: "Restore a VM from a snapshot",
+	Long: `Restore a VM from one of its snapshots, by default the newest usable one.
+
+By default the VM is rebuilt from the snapshot in place, keeping its ID, ports
+and IPs. Nova can only do this for VMs that boot from an image; for a
+volume-backed VM, use --as-new to create a new VM from the snapshot set, with
+new volumes made from each volume snapshot. The new VM gets the flavor and
+networks of the original unless --flavor and --networks are given, which they
+must be if the original has been deleted.
+
+Example:
+  vhicmd snapshot restore web-01
+  vhicmd snapshot restore web-01 web-01-20250101-020000 --as-new web-01-restored
+  vhicmd snapshot restore <deleted-vm-uuid> --as-new web-01 --flavor m1.small --networks lan`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
+		}
+		if _, err := validateTokenEndpoint(tok, "image"); err != nil {
+			return err
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+		snaps, err := api.NewClient(tok).Image().ListVMSnapshots(cmd.Context(), vmID)
+		if err != nil {
+			return err
+		}
+		ref := ""
+		if len(args) == 2 {
+			ref = args[1]
+		}
+		snap, err := pickVMSnapshot(snaps, ref)
+		if err != nil {
+			return err
+		}
+
+		if flagRestoreAsNew != "" {
+			return restoreAsNewVM(cmd, computeURL, vmID, snap)
+		}
+
+		if snap.VolumeBacked() {
+			return usageError{fmt.Errorf("snapshot %s is of a volume-backed VM, which Nova can't rebuild in place; use --as-new <name> to create a new VM from it", snap.Name)}
+		}
+		if err := confirm(fmt.Sprintf("Rebuild VM %s from snapshot %s? Its current disk contents will be lost. [y/N]: ", vmID, snap.Name)); err != nil {
+			return err
+		}
+		if err := api.RebuildVM(cmd.Context(), computeURL, tok.Value, vmID, api.RebuildVMRequest{ImageRef: snap.ID}, waitOptions("VM")); err != nil {
+			return err
+		}
+		fmt.Printf("VM %s restored from snapshot %s (%s)\n", vmID, snap.Name, snap.ID)
+		return nil
+	},
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune <vm> --keep N",
+	Short: "Delete all but the newest snapshots of a VM",
+	Long: `Delete the snapshots of a VM beyond the newest N, along with their volume
+snapshots. The snapshots to delete are listed and you are asked to confirm
+unless --yes is given.
+
+Example:
+  vhicmd snapshot prune web-01 --keep 7 --dry-run
+  vhicmd snapshot prune web-01 --keep 7 --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := validateTokenEndpoint(tok, "image"); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("keep") {
+			return usageError{fmt.Errorf("--keep is required")}
+		}
+		if flagSnapshotKeep < 0 {
+			return usageError{fmt.Errorf("--keep must not be negative")}
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+		return pruneVMSnapshots(cmd, vmID, flagSnapshotKeep, true)
+	},
+}
+
+// snapshotRows converts snapshots for PrintVMSnapshotsTable.
+func snapshotRows(snaps []api.VMSnapshot) []responseparser.VMSnapshot {
+	rows := make([]responseparser.VMSnapshot, len(snaps))
+	for i, s := range snaps {
+		ids, _ := s.VolumeSnapshotIDs()
+		rows[i] = responseparser.VMSnapshot{
+			Name:            s.Name,
+			ID:              s.ID,
+			Status:          s.Status,
+			Created:         s.CreatedAt,
+			Size:            s.Size,
+			VolumeSnapshots: len(ids),
+		}
+	}
+	return rows
+}
+
+// pickVMSnapshot finds ref, a snapshot name or ID, among snaps. An empty
+// ref picks the newest active snapshot.
+func pickVMSnapshot(snaps []api.VMSnapshot, ref string) (api.VMSnapshot, error) {
+	if ref == "" {
+		for _, s := range snaps {
+			if s.Status == "active" {
+				return s, nil
+			}
+		}
+		return api.VMSnapshot{}, &resolver.NotFoundError{Kind: "snapshot", Ref: "(newest active)"}
+	}
+
+	candidates := make([]resolver.Candidate, len(snaps))
+	for i, s := range snaps {
+		candidates[i] = resolver.Candidate{ID: s.ID, Name: s.Name}
+	}
+	c, err := resolver.Match("snapshot", ref, candidates)
+	if err != nil {
+		return api.VMSnapshot{}, err
+	}
+	for _, s := range snaps {
+		if s.ID == c.ID {
+			return s, nil
+		}
+	}
+	return api.VMSnapshot{}, &resolver.NotFoundError{Kind: "snapshot", Ref: ref}
+}
+
+// restoreAsNewVM boots a new VM from snap. For a volume-backed snapshot
+// Nova creates the volumes from the image's block device mapping; otherwise
+// the image is copied to a new boot volume, as 'create vm' does.
+func restoreAsNewVM(cmd *cobra.Command, computeURL, vmID string, snap api.VMSnapshot) error {
+	flavorRef := flagRestoreFlavor
+	var networks []string
+	if flagRestoreNetworks != "" {
+		networks = strings.Split(flagRestoreNetworks, ",")
+	}
+
+	if flavorRef == "" || len(networks) == 0 {
+		orig, err := api.GetVMDetails(computeURL, tok.Value, vmID)
+		if api.IsNotFound(err) {
+			return usageError{fmt.Errorf("VM %s no longer exists; pass --flavor and --networks for the new VM", vmID)}
+		}
+		if err != nil {
+			return err
+		}
+		if flavorRef == "" {
+			flavorRef = orig.Flavor.OriginalName
+			if flavorRef == "" {
+				flavorRef = orig.Flavor.ID
+			}
+		}
+		if len(networks) == 0 {
+			networks = vmNetworks(orig)
+		}
+		if len(networks) == 0 {
+			return usageError{fmt.Errorf("could not tell which networks VM %s is on; pass --networks", vmID)}
+		}
+	}
+
+	flavorID, err := resolveID(cmd, resolver.KindFlavor, flavorRef)
+	if err != nil {
+		return err
+	}
+	var nets []map[string]string
+	for _, n := range networks {
+		id, err := resolveID(cmd, resolver.KindNetwork, strings.TrimSpace(n))
+		if err != nil {
+			return err
+		}
+		nets = append(nets, map[string]string{"uuid": id})
+	}
+
+	server := map[string]interface{}{
+		"name":      flagRestoreAsNew,
+		"flavorRef": flavorID,
+		"networks":  nets,
+	}
+	if snap.VolumeBacked() {
+		server["imageRef"] = snap.ID
+	} else {
+		size := snap.MinDisk
+		if gb := int((snap.Size + 1<<30 - 1) >> 30); gb > size {
+			size = gb
+		}
+		server["block_device_mapping_v2"] = []map[string]interface{}{{
+			"boot_index":            "0",
+			"uuid":                  snap.ID,
+			"source_type":           "image",
+			"destination_type":      "volume",
+			"volume_size":           size,
+			"delete_on_termination": true,
+		}}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"server": server})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Creating VM %s from snapshot %s...\n", flagRestoreAsNew, snap.Name)
+	resp, err := api.CreateVMRaw(computeURL, tok.Value, body)
+	if err != nil {
+		return fmt.Errorf("failed to create VM: %v", err)
+	}
+
+	if !flagNoWait {
+		if _, err := api.WaitForStatus(cmd.Context(), computeURL, tok.Value, resp.Server.ID, waitOptions("VM"), "ACTIVE"); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("VM %s (%s) created from snapshot %s\n", flagRestoreAsNew, resp.Server.ID, snap.Name)
+	return nil
+}
+
+// vmNetworks returns the networks a VM is attached to, as IDs where VHI
+// reports them and as names otherwise.
+func vmNetworks(vm api.VMDetail) []string {
+	var networks []string
+	for _, n := range vm.HCIInfo.Network {
+		networks = append(networks, n.Network.ID)
+	}
+	if len(networks) > 0 {
+		return networks
+	}
+	for name := range vm.Addresses {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// pruneVMSnapshots deletes all but the newest keep snapshots of a VM. ask
+// lists them and asks for confirmation first.
+func pruneVMSnapshots(cmd *cobra.Command, vmID string, keep int, ask bool) error {
+	client := api.NewClient(tok)
+	snaps, err := client.Image().ListVMSnapshots(cmd.Context(), vmID)
+	if err != nil {
+		return err
+	}
+	if len(snaps) <= keep {
+		fmt.Printf("VM %s has %d snapshots; nothing to prune\n", vmID, len(snaps))
+		return nil
+	}
+	old := snaps[keep:]
+
+	if ask || flagSnapshotDryRun {
+		fmt.Printf("Snapshots of VM %s beyond the newest %d:\n", vmID, keep)
+		responseparser.PrintVMSnapshotsTable(snapshotRows(old))
+	}
+	if flagSnapshotDryRun {
+		fmt.Println("Dry run; nothing was deleted.")
+		return nil
+	}
+	if ask {
+		if err := confirm(fmt.Sprintf("Delete %d snapshots? [y/N]: ", len(old))); err != nil {
+			return err
+		}
+	}
+
+	var failed int
+	var firstErr error
+	for _, s := range old {
+		if err := deleteVMSnapshot(cmd.Context(), client, s); err != nil {
+			if cmd.Context().Err() != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Failed to delete snapshot %s: %v\n", s.Name, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fmt.Printf("Deleted snapshot %s (%s)\n", s.Name, s.ID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d snapshots could not be deleted; first error: %w", failed, len(old), firstErr)
+	}
+	return nil
+}
+
+// deleteVMSnapshot deletes a snapshot's volume snapshots and then its
+// image. Glance doesn't delete the volume snapshots by itself.
+func deleteVMSnapshot(ctx context.Context, client *api.Client, snap api.VMSnapshot) error {
+	ids, err := snap.VolumeSnapshotIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		err := client.Volume().DeleteVolumeSnapshot(ctx, id)
+		if err != nil && !api.IsNotFound(err) {
+			return fmt.Errorf("failed to delete volume snapshot %s: %w", id, err)
+		}
+	}
+	return client.Image().DeleteImage(ctx, snap.ID)
+}
+
+func init() {
+	snapshotCreateCmd.Flags().StringVar(&flagSnapshotName, "name", "", "Snapshot name (default: <vm name>-<timestamp>)")
+	snapshotCreateCmd.Flags().IntVar(&flagSnapshotKeep, "keep", 0, "After the snapshot, delete all but the newest N snapshots of the VM")
+	addWaitFlags(snapshotCreateCmd, true)
+
+	snapshotRestoreCmd.Flags().StringVar(&flagRestoreAsNew, "as-new", "", "Create a new VM with this name instead of rebuilding the VM")
+	snapshotRestoreCmd.Flags().StringVar(&flagRestoreFlavor, "flavor", "", "Flavor for --as-new (default: the original VM's)")
+	snapshotRestoreCmd.Flags().StringVar(&flagRestoreNetworks, "networks", "", "Comma-separated networks for --as-new (default: the original VM's)")
+	snapshotRestoreCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Don't ask for confirmation")
+	addWaitFlags(snapshotRestoreCmd, true)
+
+	snapshotPruneCmd.Flags().IntVar(&flagSnapshotKeep, "keep", 0, "Number of newest snapshots to keep")
+	snapshotPruneCmd.Flags().BoolVar(&flagSnapshotDryRun, "dry-run", false, "Show what would be deleted without deleting anything")
+	snapshotPruneCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Don't ask for confirmation")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
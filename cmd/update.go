@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
@@ -40,9 +42,9 @@ var vmNameCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
 		err = api.UpdateVMName(computeURL, tok.Value, vmID, newName)
@@ -56,30 +58,128 @@ var vmNameCmd = &cobra.Command{
 }
 
 var vmMetadataCmd = &cobra.Command{
-	Use:   "metadata <vm-id> <key> <value>",
+	Use:   "metadata [vm-id] <key> <value>",
 	Short: "Update VM metadata key-value pair",
-	Args:  cobra.ExactArgs(3),
+	Long: `Set a metadata key on a VM. Without a VM ID the key is set on every VM
+picked by --selector, --metadata or --from-file.
+
+Example:
+  vhicmd update vm metadata web-01 env production
+  vhicmd update vm metadata --selector 'name=web-*' env production`,
+	Args: cobra.RangeArgs(2, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		vmID := args[0]
-		key := args[1]
-		value := args[2]
+		var refs []string
+		if len(args) == 3 {
+			refs, args = args[:1], args[1:]
+		} else if len(flagSelectors) == 0 && len(flagMetadataSel) == 0 && flagFromFile == "" {
+			return usageError{fmt.Errorf("requires a VM ID, key and value, or a key and value with --selector, --metadata or --from-file")}
+		}
+		key := args[0]
+		value := args[1]
 
 		computeURL, err := validateTokenEndpoint(tok, "compute")
 		if err != nil {
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		targets, bulk, err := selectVMs(cmd, refs)
+		if err != nil {
+			return err
+		}
+
+		return runOnVMs(cmd, "update", targets, bulk, func(ctx context.Context, t vmTarget) error {
+			if err := api.UpdateVMMetadataItem(computeURL, tok.Value, t.ID, key, value); err != nil {
+				return err
+			}
+			fmt.Printf("Updated metadata %s=%s for VM %s\n", key, value, t.ID)
+			return nil
+		})
+	},
+}
+
+var vmTagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage VM tags",
+}
+
+var vmTagAddCmd = &cobra.Command{
+	Use:   "add <vm-id> <tag>...",
+	Short: "Add tags to a VM",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
 		}
 
-		err = api.UpdateVMMetadataItem(computeURL, tok.Value, vmID, key, value)
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Updated metadata %s=%s for VM %s\n", key, value, vmID)
+		for _, tag := range args[1:] {
+			if err := api.AddVMTag(computeURL, tok.Value, vmID, tag); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Added tags %s to VM %s\n", strings.Join(args[1:], ", "), vmID)
+		return nil
+	},
+}
+
+var vmTagRemoveCmd = &cobra.Command{
+	Use:     "remove <vm-id> <tag>...",
+	Aliases: []string{"rm"},
+	Short:   "Remove tags from a VM",
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range args[1:] {
+			if err := api.RemoveVMTag(computeURL, tok.Value, vmID, tag); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Removed tags %s from VM %s\n", strings.Join(args[1:], ", "), vmID)
+		return nil
+	},
+}
+
+var vmTagSetCmd = &cobra.Command{
+	Use:   "set <vm-id> [tag]...",
+	Short: "Replace all tags on a VM (no tags clears them)",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		computeURL, err := validateTokenEndpoint(tok, "compute")
+		if err != nil {
+			return err
+		}
+
+		vmID, err := resolveID(cmd, resolver.KindVM, args[0])
+		if err != nil {
+			return err
+		}
+
+		tags, err := api.SetVMTags(computeURL, tok.Value, vmID, args[1:])
+		if err != nil {
+			return err
+		}
+
+		if len(tags) == 0 {
+			fmt.Printf("Cleared tags on VM %s\n", vmID)
+			return nil
+		}
+		fmt.Printf("Tags on VM %s: %s\n", vmID, strings.Join(tags, ", "))
 		return nil
 	},
 }
@@ -99,12 +199,12 @@ After starting, use 'confirm' to accept or 'revert' to cancel.`,
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
-		flavorID, err := api.GetFlavorIDByName(computeURL, tok.Value, flavor)
+		flavorID, err := resolveID(cmd, resolver.KindFlavor, flavor)
 		if err != nil {
 			return err
 		}
@@ -115,14 +215,26 @@ After starting, use 'confirm' to accept or 'revert' to cancel.`,
 		}
 
 		fmt.Printf("Started flavor change for VM %s to %s\n", vmID, flavor)
-		vm, err := api.GetVMDetails(computeURL, tok.Value, vmID)
-		if err != nil {
-			return err
-		}
-		if vm.Status == "SHUTOFF" {
-			fmt.Printf("VM is currently shut down. The flavor change should be automatically confirmed in a few minutes.\n")
+		if flagNoWait {
+			vm, err := api.GetVMDetails(computeURL, tok.Value, vmID)
+			if err != nil {
+				return err
+			}
+			if vm.Status == "SHUTOFF" {
+				fmt.Printf("VM is currently shut down. The flavor change should be automatically confirmed in a few minutes.\n")
+			}
+		} else {
+			vm, err := api.WaitForStatus(cmd.Context(), computeURL, tok.Value, vmID, waitOptions("VM"), "VERIFY_RESIZE", "ACTIVE", "SHUTOFF")
+			if err != nil {
+				return err
+			}
+			if vm.Status != "VERIFY_RESIZE" {
+				fmt.Printf("VM %s is %s with flavor %s; the change needed no confirmation\n", vmID, vm.Status, vm.Flavor.OriginalName)
+				return nil
+			}
+			fmt.Printf("Flavor change is ready for confirmation\n")
 		}
-		fmt.Printf("Once the change is ready, use either:\n")
+		fmt.Printf("To finish, use either:\n")
 		fmt.Printf("  - 'vhicmd update vm flavor confirm %s' to accept the change\n", vmID)
 		fmt.Printf("  - 'vhicmd update vm flavor revert %s' to cancel the change\n", vmID)
 		fmt.Printf("It is possible that the confirm call will be automatically accepted depending on specific admin settings.\n")
@@ -144,15 +256,20 @@ Only use after 'start' command has completed.`,
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
 		err = api.ConfirmResize(computeURL, tok.Value, vmID)
 		if err != nil {
 			return err
 		}
+		if !flagNoWait {
+			if _, err := api.WaitForStatus(cmd.Context(), computeURL, tok.Value, vmID, waitOptions("VM"), "ACTIVE", "SHUTOFF"); err != nil {
+				return err
+			}
+		}
 
 		fmt.Printf("Confirmed flavor change for VM %s\n", vmID)
 		return nil
@@ -173,15 +290,20 @@ Only use after 'start' command has completed.`,
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
 		}
 
 		err = api.RevertResize(computeURL, tok.Value, vmID)
 		if err != nil {
 			return err
 		}
+		if !flagNoWait {
+			if _, err := api.WaitForStatus(cmd.Context(), computeURL, tok.Value, vmID, waitOptions("VM"), "ACTIVE", "SHUTOFF"); err != nil {
+				return err
+			}
+		}
 
 		fmt.Printf("Reverted flavor change for VM %s\n", vmID)
 		return nil
@@ -251,9 +373,9 @@ var imageVisibilityCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		err = api.UpdateImageVisibility(imageURL, tok.Value, imageID, visibility)
@@ -284,9 +406,9 @@ var imageMemberStatusCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		err = api.UpdateImageMemberStatus(imageURL, tok.Value, imageID, memberID, status)
@@ -316,9 +438,9 @@ var imageTypeCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetImageIDByName(imageURL, tok.Value, imageID)
-		if err == nil {
-			imageID = id
+		imageID, err = resolveID(cmd, resolver.KindImage, imageID)
+		if err != nil {
+			return err
 		}
 
 		if imageType == "i440fx" {
@@ -348,10 +470,14 @@ var volumeAttachCmd = &cobra.Command{
 			return err
 		}
 
-		// Get VM ID if name was provided
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
+		}
+
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
 		}
 
 		err = api.AttachVolume(computeURL, tok.Value, vmID, volumeID)
@@ -377,10 +503,14 @@ var volumeDetachCmd = &cobra.Command{
 			return err
 		}
 
-		// Get VM ID if name was provided
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
+		}
+
+		volumeID, err = resolveID(cmd, resolver.KindVolume, volumeID)
+		if err != nil {
+			return err
 		}
 
 		err = api.DetachVolume(computeURL, tok.Value, vmID, volumeID)
@@ -411,9 +541,14 @@ var attachPortCmd = &cobra.Command{
 			return err
 		}
 
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
+		}
+
+		portID, err = resolveID(cmd, resolver.KindPort, portID)
+		if err != nil {
+			return err
 		}
 
 		resp, err := api.AttachNetworkToVM(networkURL, computeURL, tok.Value, vmID, "", portID, nil)
@@ -440,10 +575,14 @@ var detachPortCmd = &cobra.Command{
 			return err
 		}
 
-		// Get VM ID if name was provided
-		id, err := api.GetVMIDByName(computeURL, tok.Value, vmID)
-		if err == nil {
-			vmID = id
+		vmID, err = resolveID(cmd, resolver.KindVM, vmID)
+		if err != nil {
+			return err
+		}
+
+		portID, err = resolveID(cmd, resolver.KindPort, portID)
+		if err != nil {
+			return err
 		}
 
 		err = api.DetachNetworkFromVM(computeURL, tok.Value, vmID, portID)
@@ -469,9 +608,9 @@ var updatePortCmd = &cobra.Command{
 		}
 
 		// Resolve port by name or ID
-		portID, err := api.GetPortIDByName(networkURL, tok.Value, portRef)
+		portID, err := resolveID(cmd, resolver.KindPort, portRef)
 		if err != nil {
-			return fmt.Errorf("failed to resolve port %q: %v", portRef, err)
+			return err
 		}
 
 		hasAllowedPairs := cmd.Flags().Changed("allowed-address-pairs")
@@ -525,18 +664,28 @@ var updatePortCmd = &cobra.Command{
 func init() {
 	// VM subcommands
 	updateVMCmd.AddCommand(vmNameCmd)
+	addBulkFlags(vmMetadataCmd)
 	updateVMCmd.AddCommand(vmMetadataCmd)
 	updateVMCmd.AddCommand(vmFlavorCmd)
+	updateVMCmd.AddCommand(vmTagCmd)
 	updateVMCmd.AddCommand(attachPortCmd)
 	updateVMCmd.AddCommand(detachPortCmd)
 	updateVMCmd.AddCommand(volumeAttachCmd)
 	updateVMCmd.AddCommand(volumeDetachCmd)
 
 	// Flavor subcommands
+	for _, c := range []*cobra.Command{flavorStartCmd, flavorConfirmCmd, flavorRevertCmd} {
+		addWaitFlags(c, true)
+	}
 	vmFlavorCmd.AddCommand(flavorStartCmd)
 	vmFlavorCmd.AddCommand(flavorConfirmCmd)
 	vmFlavorCmd.AddCommand(flavorRevertCmd)
 
+	// Tag subcommands
+	vmTagCmd.AddCommand(vmTagAddCmd)
+	vmTagCmd.AddCommand(vmTagRemoveCmd)
+	vmTagCmd.AddCommand(vmTagSetCmd)
+
 	// Volume subcommands
 	updateVolumeCmd.AddCommand(volumeTypeAccessCmd)
 	updateVolumeCmd.AddCommand(volumeAttachCmd)
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -17,10 +16,14 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/facette/natsort"
 	"github.com/jessegalley/vhicmd/api"
+	"github.com/jessegalley/vhicmd/internal/httpclient"
+	"github.com/jessegalley/vhicmd/internal/resolver"
 	"github.com/jessegalley/vhicmd/internal/responseparser"
+	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
@@ -42,16 +45,19 @@ func readUsernameFromStdin() (string, error) {
 // on stdout and then reads in and returns what they enter
 // does not echo what they type
 func readPasswordFromStdin() (string, error) {
-	fmt.Print("password: ")
-	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
+	return readSecretFromStdin("password: ")
+}
+
+// readSecretFromStdin prompts for a secret without echoing it
+func readSecretFromStdin(prompt string) (string, error) {
+	fmt.Print(prompt)
+	byteSecret, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
-		log.Fatalf("failed to read password: %v", err)
+		log.Fatalf("failed to read secret: %v", err)
 	}
-	fmt.Println() // add a newline after password input
-
-	password := string(bytePassword)
+	fmt.Println() // add a newline after secret input
 
-	return password, nil
+	return string(byteSecret), nil
 }
 
 // readConfirmation() prompts the user for a yes/no confirmation
@@ -143,6 +149,39 @@ func validateTokenEndpoint(tok api.Token, endpoint string) (string, error) {
 	return url, nil
 }
 
+var (
+	flagWaitTimeout time.Duration
+	flagNoWait      bool
+)
+
+// addWaitFlags adds --wait-timeout, and --no-wait if the command can skip
+// its final wait, to a command that waits for resources to settle.
+func addWaitFlags(cmd *cobra.Command, noWait bool) {
+	cmd.Flags().DurationVar(&flagWaitTimeout, "wait-timeout", api.DefaultWaitTimeout, "How long to wait for each resource to become ready (e.g. 45m, 2h)")
+	if noWait {
+		cmd.Flags().BoolVar(&flagNoWait, "no-wait", false, "Return once the request is accepted instead of waiting for it to finish")
+	}
+}
+
+// waitOptions() returns poll settings from the wait flags. Status changes of
+// the resource are reported on stderr, keeping stdout for the result.
+func waitOptions(what string) api.WaitOptions {
+	return api.WaitOptions{
+		Timeout: flagWaitTimeout,
+		NoWait:  flagNoWait,
+		OnProgress: func(status string) {
+			fmt.Fprintf(os.Stderr, "%s status: %s\n", what, status)
+		},
+	}
+}
+
+// resolveID() resolves a resource reference (ID, name, unique prefix or
+// name@project) to an ID. Failed or ambiguous lookups are errors; the raw
+// reference is never used as a fallback.
+func resolveID(cmd *cobra.Command, kind resolver.Kind, ref string) (string, error) {
+	return resolver.New(api.NewClient(tok), tok).ID(cmd.Context(), kind, ref)
+}
+
 // readAndEncodeUserData() reads the user data file at the given path
 // Commonly used for cloud-init scripts
 func readAndEncodeUserData(path string) (string, error) {
@@ -319,15 +358,10 @@ func validateIPs(ips []string) error {
 	return nil
 }
 
-// fetchFileOrURL fetches a file from a local path or a URL, allowing insecure TLS
+// fetchFileOrURL fetches a file from a local path or a URL
 func fetchFileOrURL(path string) ([]byte, error) {
 	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client := &http.Client{Transport: tr}
-
-		resp, err := client.Get(path)
+		resp, err := httpclient.Fetch(path)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch URL: %v", err)
 		}
@@ -18,6 +18,26 @@ type Config struct {
 	Networks string `mapstructure:"networks"`
 	FlavorID string `mapstructure:"flavor_id"`
 	ImageID  string `mapstructure:"image_id"`
+
+	Region    string `mapstructure:"region"`
+	Interface string `mapstructure:"interface"`
+
+	CACert   string `mapstructure:"cacert"`
+	Cert     string `mapstructure:"cert"`
+	Key      string `mapstructure:"key"`
+	Insecure bool   `mapstructure:"insecure"`
+	Proxy    string `mapstructure:"proxy"`
+
+	RetryMaxAttempts int    `mapstructure:"retry_max_attempts"`
+	RetryBaseDelay   string `mapstructure:"retry_base_delay"`
+
+	AuthMethod          string `mapstructure:"auth_method"`
+	AppCredentialID     string `mapstructure:"application_credential_id"`
+	AppCredentialSecret string `mapstructure:"application_credential_secret"`
+	AuthToken           string `mapstructure:"auth_token"`
+
+	CurrentContext string             `mapstructure:"current_context"`
+	Contexts       map[string]Context `mapstructure:"contexts"`
 }
 
 // GetDefaultConfigPath returns the default path for the config file
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentContextKey holds the name of the context used when --context is not given
+const CurrentContextKey = "current_context"
+
+// ContextKeys are the settings a named context can hold. Any that are set
+// in the active context override the top-level values.
+var ContextKeys = []string{
+	"host",
+	"domain",
+	"project",
+	"username",
+	"password",
+	"networks",
+	"flavor_id",
+	"image_id",
+	"region",
+	"interface",
+}
+
+// Context is a named set of connection settings, kubectl style:
+//
+//	current_context: staging
+//	contexts:
+//	  staging:
+//	    host: vhi-staging.example.com
+//	    domain: mydomain
+//	    project: myproject
+type Context struct {
+	Name     string `mapstructure:"-"`
+	Host     string `mapstructure:"host"`
+	Domain   string `mapstructure:"domain"`
+	Project  string `mapstructure:"project"`
+	Username string `mapstructure:"username"`
+	Networks string `mapstructure:"networks"`
+	FlavorID string `mapstructure:"flavor_id"`
+	ImageID  string `mapstructure:"image_id"`
+	Region   string `mapstructure:"region"`
+	// Interface is the endpoint interface: public, internal or admin
+	Interface string `mapstructure:"interface"`
+}
+
+// IsContextKey reports whether key can be set per context.
+func IsContextKey(key string) bool {
+	for _, k := range ContextKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextSettingKey returns the viper key for a setting inside a context.
+func ContextSettingKey(name, key string) string {
+	return "contexts." + name + "." + key
+}
+
+// ValidateContextName rejects names viper can't use as a key.
+func ValidateContextName(name string) error {
+	if name == "" {
+		return fmt.Errorf("context name must not be empty")
+	}
+	if strings.ContainsAny(name, ". ") {
+		return fmt.Errorf("invalid context name '%s': must not contain dots or spaces", name)
+	}
+	return nil
+}
+
+// HasContext reports whether a context with this name exists.
+func HasContext(v *viper.Viper, name string) bool {
+	return v.IsSet("contexts." + name)
+}
+
+// ListContexts returns the contexts in the config sorted by name.
+func ListContexts(v *viper.Viper) ([]Context, error) {
+	var contexts []Context
+	for name := range v.GetStringMap("contexts") {
+		var c Context
+		if err := v.UnmarshalKey("contexts."+name, &c); err != nil {
+			return nil, fmt.Errorf("invalid context '%s': %v", name, err)
+		}
+		c.Name = name
+		contexts = append(contexts, c)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+	return contexts, nil
+}
+
+// ApplyContext overlays the settings of the named context onto v in memory.
+// The overlay takes precedence over the config file and environment, so v
+// must not be written back with WriteConfig afterwards.
+func ApplyContext(v *viper.Viper, name string) error {
+	if err := ValidateContextName(name); err != nil {
+		return err
+	}
+	if !HasContext(v, name) {
+		return fmt.Errorf("context '%s' not found in config", name)
+	}
+
+	for _, key := range ContextKeys {
+		if value := v.GetString(ContextSettingKey(name, key)); value != "" {
+			v.Set(key, value)
+		}
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -28,107 +30,199 @@ func SendRequest(url string, jsonData []byte) (*http.Response, error) {
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", userAgent)
 
-	if viper.GetBool("debug") {
-		printDebugDivider("request")
-		fmt.Printf("\033[1;32mURL:\033[0m %s\n", url)
-		fmt.Printf("\033[1;32mMethod:\033[0m %s\n", req.Method)
-		printDebugDivider("request headers")
-		printHeaders(req.Header)
-		if len(jsonData) > 0 {
-			printDebugDivider("request body")
-			fmt.Println(prettyPrintJSON(jsonData))
-		}
-	}
+	debugRequest(req, jsonData)
 
 	client := &http.Client{
-		Timeout: requestTimeout,
+		Transport: newTransport(),
+		Timeout:   requestTimeout,
 	}
+	start := time.Now()
 	resp, err := client.Do(req)
+	var respBody []byte
+	if err == nil && (tracing() || debugging()) {
+		respBody = captureBody(resp)
+	}
+	traceExchange(start, 0, req, jsonData, resp, respBody, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 
-	if viper.GetBool("debug") {
-		printDebugDivider("response")
-		fmt.Printf("\033[1;32mStatus:\033[0m %s\n", resp.Status)
-		printDebugDivider("response headers")
-		printHeaders(resp.Header)
-
-		if resp.Body != nil {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			if len(bodyBytes) > 0 {
-				printDebugDivider("response body")
-				fmt.Println(prettyPrintJSON(bodyBytes))
-			}
-			resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
-	}
+	debugResponse(resp, respBody)
 
 	return resp, nil
 }
 
-// SendRequestWithToken can handle both GET and POST requests with a timeout and a custom User-Agent.
-func SendRequestWithToken(method, url, token string, body io.Reader) (*http.Response, error) {
-	// Read and reassign the body for logging if it's not nil
-	var bodyBytes []byte
-	if body != nil && viper.GetBool("debug") {
-		bodyBytes, _ = io.ReadAll(body)
-		body = bytes.NewReader(bodyBytes) // Rebuild the body for reuse
+// Client sends API requests over a single http.Client so that connections
+// are pooled across calls. The zero value is not usable; use New.
+type Client struct {
+	HTTP *http.Client
+
+	tokens tokenRefresh
+}
+
+// New returns a Client that sends requests through transport. A nil
+// transport uses a clone of http.DefaultTransport.
+func New(transport http.RoundTripper) *Client {
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return &Client{
+		HTTP: &http.Client{
+			Transport: transport,
+			Timeout:   requestTimeout,
+		},
 	}
+}
 
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+// Default is the shared Client used by the package-level helpers.
+var Default = New(nil)
+
+type headerKey struct{}
+
+// WithHeader returns a copy of ctx under which Client requests carry the
+// header key: value, e.g. a service-specific API version.
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	h := http.Header{}
+	if prev := contextHeaders(ctx); prev != nil {
+		h = prev.Clone()
 	}
+	h.Set(key, value)
+	return context.WithValue(ctx, headerKey{}, h)
+}
+
+func contextHeaders(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h
+}
 
-	// Add headers
+// SendRequestWithToken can handle both GET and POST requests with a timeout and a custom User-Agent.
+func SendRequestWithToken(method, url, token string, body io.Reader) (*http.Response, error) {
+	return Default.Do(context.Background(), method, url, token, body)
+}
+
+// Do sends a JSON API request, bound to ctx, with the token in X-Auth-Token.
+func (c *Client) Do(ctx context.Context, method, url, token string, body io.Reader) (*http.Response, error) {
+	contentType := ""
 	if (method == "POST" || method == "PATCH") && body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		contentType = "application/json"
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", userAgent)
-	if token != "" {
-		req.Header.Set("X-Auth-Token", token)
-	}
-
-	// We need this header set for BlockDeviceMappingV2.VolumeType
-	req.Header.Set("X-OpenStack-Nova-API-Version", "2.72")
-
-	if viper.GetBool("debug") {
-		printDebugDivider("request")
-		fmt.Printf("\033[1;32mURL:\033[0m %s\n", url)
-		fmt.Printf("\033[1;32mMethod:\033[0m %s\n", req.Method)
-		printDebugDivider("request headers")
-		printHeaders(req.Header)
-		if len(bodyBytes) > 0 {
-			printDebugDivider("request body")
-			fmt.Println(prettyPrintJSON(bodyBytes))
+	return c.send(ctx, method, url, token, contentType, body)
+}
+
+// DoImagePatch sends a Glance JSON-patch request bound to ctx.
+func (c *Client) DoImagePatch(ctx context.Context, url, token string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, "PATCH", url, token, "application/openstack-images-v2.1-json-patch", body)
+}
+
+func (c *Client) send(ctx context.Context, method, url, token, contentType string, body io.Reader) (*http.Response, error) {
+	// Buffer the body so it can be logged and replayed on retry
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
 		}
 	}
 
-	// Send the request
-	client := &http.Client{Timeout: requestTimeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	policy := retryPolicy{maxAttempts: 1}
+	if isIdempotent(method) {
+		policy = contextRetryPolicy(ctx)
 	}
 
-	if viper.GetBool("debug") {
-		printDebugDivider("response")
-		fmt.Printf("\033[1;32mStatus:\033[0m %s\n", resp.Status)
-		printDebugDivider("response headers")
-		printHeaders(resp.Header)
+	token = c.CurrentToken(token)
+	refreshed := false
+
+	var resp *http.Response
+	var respBody []byte
+	for attempt := 1; ; attempt++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(bodyBytes)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+
+		// Add headers
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		if token != "" {
+			req.Header.Set("X-Auth-Token", token)
+		}
+
+		for key, values := range contextHeaders(ctx) {
+			req.Header[key] = values
+		}
+
+		if attempt == 1 {
+			debugRequest(req, bodyBytes)
+		}
 
-		if resp.Body != nil {
-			respBytes, _ := io.ReadAll(resp.Body)
-			if len(respBytes) > 0 {
-				printDebugDivider("response body")
-				fmt.Println(prettyPrintJSON(respBytes))
+		// Send the request
+		start := time.Now()
+		resp, err = c.HTTP.Do(req)
+		respBody = nil
+		if err == nil && (tracing() || debugging()) {
+			respBody = captureBody(resp)
+		}
+		traceExchange(start, attempt, req, bodyBytes, resp, respBody, err)
+
+		// An expired or revoked token gets one re-authentication and replay,
+		// independent of the retry budget
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && token != "" && !refreshed {
+			refreshed = true
+			fresh, refreshErr := c.refreshToken(ctx, token)
+			if refreshErr == nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				token = fresh
+				attempt--
+				continue
+			}
+			if debugging() && refreshErr != errNoRefresher {
+				fmt.Fprintf(os.Stderr, "\033[1;33mToken refresh failed:\033[0m %v\n", refreshErr)
 			}
-			resp.Body = io.NopCloser(bytes.NewBuffer(respBytes))
+		}
+
+		if attempt >= policy.maxAttempts {
+			if err != nil {
+				return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+			}
+			break
+		}
+
+		var reason string
+		switch {
+		case err != nil && retryableError(err):
+			reason = err.Error()
+		case err != nil:
+			return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		case retryableStatus(resp.StatusCode):
+			reason = resp.Status
+		}
+		if reason == "" {
+			break
+		}
+
+		delay := policy.retryDelay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		printRetry(method, url, attempt, policy.maxAttempts, reason, delay)
+		if err := SleepContext(ctx, delay); err != nil {
+			return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 		}
 	}
 
+	debugResponse(resp, respBody)
+
 	return resp, nil
 }
 
@@ -145,68 +239,114 @@ func SendLargePutRequest(url, token string, data io.Reader) (*http.Response, err
 	req.Header.Set("X-Auth-Token", token)
 
 	// No timeout for large uploads
-	client := &http.Client{Timeout: 0}
+	client := &http.Client{Transport: newTransport(), Timeout: 0}
 
-	if viper.GetBool("debug") {
-		printDebugDivider("request")
-		fmt.Printf("\033[1;32mURL:\033[0m %s\n", url)
-		fmt.Printf("\033[1;32mMethod:\033[0m %s\n", req.Method)
-		printDebugDivider("request headers")
-		printHeaders(req.Header)
-	}
+	debugRequest(req, nil)
 
+	start := time.Now()
 	resp, err := client.Do(req)
+	traceExchange(start, 0, req, nil, resp, nil, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 
-	if viper.GetBool("debug") {
-		printDebugDivider("response")
-		fmt.Printf("\033[1;32mStatus:\033[0m %s\n", resp.Status)
-		printDebugDivider("response headers")
-		printHeaders(resp.Header)
-	}
+	debugResponse(resp, nil)
 
 	return resp, nil
 }
 
-func SendImagePatch(url, token string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest("PATCH", url, body)
+// Fetch sends an unauthenticated GET for a file given by URL, such as a
+// user data script, with the configured TLS and proxy options.
+func Fetch(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-
-	req.Header.Set("Content-Type", "application/openstack-images-v2.1-json-patch")
-	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", userAgent)
-	if token != "" {
-		req.Header.Set("X-Auth-Token", token)
+
+	client := &http.Client{Transport: newTransport(), Timeout: requestTimeout}
+
+	debugRequest(req, nil)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	traceExchange(start, 0, req, nil, resp, nil, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 
-	client := &http.Client{Timeout: requestTimeout}
-	return client.Do(req)
+	debugResponse(resp, nil)
+
+	return resp, nil
+}
+
+// SendImagePatch sends a Glance JSON-patch request through the Default client.
+func SendImagePatch(url, token string, body io.Reader) (*http.Response, error) {
+	return Default.DoImagePatch(context.Background(), url, token, body)
 }
 
 // -- DEBUGGING --
 
+// debugging reports whether --debug output is on. Debug output goes to
+// stderr so it never mixes with --json output.
+func debugging() bool {
+	return viper.GetBool("debug")
+}
+
+// debugRequest prints a request under --debug, with secrets redacted.
+func debugRequest(req *http.Request, body []byte) {
+	if !debugging() {
+		return
+	}
+	printDebugDivider("request")
+	fmt.Fprintf(os.Stderr, "\033[1;32mURL:\033[0m %s\n", req.URL)
+	fmt.Fprintf(os.Stderr, "\033[1;32mMethod:\033[0m %s\n", req.Method)
+	printDebugDivider("request headers")
+	printHeaders(req.Header)
+	if len(body) > 0 {
+		printDebugDivider("request body")
+		fmt.Fprintln(os.Stderr, prettyPrintJSON(body))
+	}
+}
+
+// debugResponse prints a response under --debug. body is the captured JSON
+// body, if any.
+func debugResponse(resp *http.Response, body []byte) {
+	if !debugging() {
+		return
+	}
+	printDebugDivider("response")
+	fmt.Fprintf(os.Stderr, "\033[1;32mStatus:\033[0m %s\n", resp.Status)
+	printDebugDivider("response headers")
+	printHeaders(resp.Header)
+	if len(body) > 0 {
+		printDebugDivider("response body")
+		fmt.Fprintln(os.Stderr, prettyPrintJSON(body))
+	}
+}
+
 // printDebugDivider prints a section divider
 func printDebugDivider(title string) {
-	fmt.Printf("\n\033[1;36m=== %s ===\033[0m\n", strings.ToUpper(title))
+	fmt.Fprintf(os.Stderr, "\n\033[1;36m=== %s ===\033[0m\n", strings.ToUpper(title))
 }
 
-// prettyPrintJSON formats JSON with indentation
+// prettyPrintJSON formats JSON with indentation, redacting secrets
 func prettyPrintJSON(data []byte) string {
-	var prettyJSON bytes.Buffer
-	err := json.Indent(&prettyJSON, data, "", "    ")
+	v, ok := RedactJSON(data)
+	if !ok {
+		return fmt.Sprintf("<%d bytes, not JSON>", len(data))
+	}
+	pretty, err := json.MarshalIndent(v, "", "    ")
 	if err != nil {
-		return string(data) // Fallback to raw if not valid JSON
+		return string(data)
 	}
-	return prettyJSON.String()
+	return string(pretty)
 }
 
-// printHeaders pretty prints HTTP headers
+// printHeaders pretty prints HTTP headers, redacting credentials
 func printHeaders(headers http.Header) {
-	for key, values := range headers {
-		fmt.Printf("    \033[1;33m%s\033[0m: %s\n", key, strings.Join(values, ", "))
+	redactedHeaders := RedactHeaders(headers)
+	for _, key := range sortedKeys(redactedHeaders) {
+		fmt.Fprintf(os.Stderr, "    \033[1;33m%s\033[0m: %s\n", key, redactedHeaders[key])
 	}
 }
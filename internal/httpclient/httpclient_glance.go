@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -11,8 +12,6 @@ import (
 	"syscall"
 	"text/tabwriter"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 type countingReader struct {
@@ -28,7 +27,9 @@ func (cr *countingReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
-func UploadBigFile(url, token string, data io.Reader) (*http.Response, error) {
+// UploadBigFile streams data to url with a PUT, reporting progress on
+// stdout. Cancelling ctx aborts the transfer.
+func UploadBigFile(ctx context.Context, url, token string, data io.Reader) (*http.Response, error) {
 	var size int64
 	if f, ok := data.(*os.File); ok {
 		info, err := f.Stat()
@@ -48,20 +49,21 @@ func UploadBigFile(url, token string, data io.Reader) (*http.Response, error) {
 		uploaded: &uploadedBytes,
 	}
 
-	req, err := http.NewRequest("PUT", url, cr)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, cr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
-	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("X-Auth-Token", Default.CurrentToken(token))
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", size))
 	req.Header.Del("Expect")
 
-	if viper.GetBool("debug") {
-		fmt.Printf("Starting upload (size: %d bytes)\n", size)
+	if debugging() {
+		fmt.Fprintf(os.Stderr, "Starting upload (size: %d bytes)\n", size)
 	}
 
 	transport := &http.Transport{
+		Proxy:                 proxyFunc,
 		DisableCompression:    false,
 		ForceAttemptHTTP2:     false,
 		ExpectContinueTimeout: 2 * time.Second,
@@ -78,6 +80,7 @@ func UploadBigFile(url, token string, data io.Reader) (*http.Response, error) {
 		}).DialContext,
 		WriteBufferSize: 64 * 1024 * 1024,
 		ReadBufferSize:  64 * 1024 * 1024,
+		TLSClientConfig: tlsConfig,
 	}
 
 	client := &http.Client{
@@ -91,8 +94,12 @@ func UploadBigFile(url, token string, data io.Reader) (*http.Response, error) {
 
 	resp, err := client.Do(req)
 	close(stopProgress)
+	traceExchange(startTime, 0, req, nil, resp, nil, err)
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("upload failed: %v", err)
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// proxyFunc picks the proxy for every request path: Default, auth requests
+// and image uploads. Without a configured proxy the standard HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY environment variables apply.
+var proxyFunc = http.ProxyFromEnvironment
+
+// ParseProxy validates a proxy URL. http and https proxies are used with
+// CONNECT; socks5 and socks5h go through a SOCKS5 proxy.
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL '%s': %v", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL '%s': scheme must be http, https, socks5 or socks5h", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s': no host", proxy)
+	}
+	return u, nil
+}
+
+// ConfigureProxy sends all requests through proxy. An empty proxy falls
+// back to the environment.
+func ConfigureProxy(proxy string) error {
+	fn := http.ProxyFromEnvironment
+	if proxy != "" {
+		u, err := ParseProxy(proxy)
+		if err != nil {
+			return err
+		}
+		fn = http.ProxyURL(u)
+	}
+	proxyFunc = fn
+	Default.SetProxy(fn)
+	return nil
+}
+
+// SetProxy sets the proxy of the client's transport, if it is an
+// *http.Transport.
+func (c *Client) SetProxy(fn func(*http.Request) (*url.URL, error)) {
+	if t, ok := c.HTTP.Transport.(*http.Transport); ok {
+		t.Proxy = fn
+	}
+}
@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseProxy(t *testing.T) {
+	for _, proxy := range []string{"http://jump:3128", "https://jump:3129", "socks5://127.0.0.1:1080", "socks5h://jump:1080"} {
+		if _, err := ParseProxy(proxy); err != nil {
+			t.Errorf("ParseProxy(%q): %v", proxy, err)
+		}
+	}
+	for _, proxy := range []string{"jump:3128", "ftp://jump", "socks4://jump:1080", "http://"} {
+		if _, err := ParseProxy(proxy); err == nil {
+			t.Errorf("ParseProxy(%q): expected an error", proxy)
+		}
+	}
+}
+
+func TestConfigureProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy sees the absolute target URL
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	saved := Default
+	Default = New(nil)
+	defer func() {
+		Default = saved
+		ConfigureProxy("")
+	}()
+
+	if err := ConfigureProxy(proxy.URL); err != nil {
+		t.Fatalf("ConfigureProxy: %v", err)
+	}
+
+	resp, err := Default.Do(context.Background(), "GET", "http://vhi.invalid:8774/v2.1/servers", "", nil)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://vhi.invalid:8774/v2.1/servers" {
+		t.Errorf("proxy saw %q; want the API URL", proxied)
+	}
+
+	// One-off clients for auth and uploads use the same proxy
+	proxied = ""
+	resp, err = SendRequest("http://vhi.invalid:5000/v3/auth/tokens", []byte("{}"))
+	if err != nil {
+		t.Fatalf("SendRequest: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://vhi.invalid:5000/v3/auth/tokens" {
+		t.Errorf("proxy saw %q for auth; want the auth URL", proxied)
+	}
+}
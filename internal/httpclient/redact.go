@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redacted replaces secrets in debug output and traces
+const redacted = "REDACTED"
+
+// sensitiveHeaders carry tokens or credentials
+var sensitiveHeaders = map[string]bool{
+	"X-Auth-Token":        true,
+	"X-Subject-Token":     true,
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// sensitiveKeys are JSON fields whose values are never logged. Any key
+// containing "password" or "secret" is redacted as well.
+var sensitiveKeys = map[string]bool{
+	"user_data": true,
+	"adminpass": true,
+}
+
+// RedactHeaders flattens headers for logging with credentials replaced.
+func RedactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			out[key] = redacted
+			continue
+		}
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// RedactJSON decodes a JSON body and replaces the values of sensitive
+// fields. It reports false if data is not JSON.
+func RedactJSON(data []byte) (interface{}, bool) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, false
+	}
+	return redactValue("", v), true
+}
+
+func redactValue(parent string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			switch child.(type) {
+			case map[string]interface{}, []interface{}:
+				// e.g. the "password" auth method object; only its
+				// values are secret
+				val[key] = redactValue(key, child)
+			default:
+				if child != nil && sensitiveKey(parent, key) {
+					val[key] = redacted
+				}
+			}
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactValue(parent, child)
+		}
+		return val
+	}
+	return v
+}
+
+// sensitiveKey reports whether a JSON field holds a secret. The token auth
+// method sends the existing token as {"token": {"id": ...}}.
+func sensitiveKey(parent, key string) bool {
+	k := strings.ToLower(key)
+	if sensitiveKeys[k] || strings.Contains(k, "password") || strings.Contains(k, "secret") {
+		return true
+	}
+	return strings.ToLower(parent) == "token" && k == "id"
+}
+
+// sortedKeys returns the keys of m in order, for stable debug output.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
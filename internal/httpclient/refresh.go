@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// errNoRefresher is returned when no TokenRefresher has been registered.
+var errNoRefresher = errors.New("token refresh not available")
+
+// TokenRefresher obtains a new token after the API rejected stale with 401.
+type TokenRefresher func(ctx context.Context, stale string) (string, error)
+
+// tokenRefresh holds the refresher and the tokens it has already replaced,
+// so callers still holding a stale token are switched to the new one
+// without another round trip.
+type tokenRefresh struct {
+	mu       sync.Mutex
+	refresh  TokenRefresher
+	replaced map[string]string
+
+	// refreshing serializes refreshes so concurrent 401s re-authenticate once.
+	refreshing sync.Mutex
+}
+
+// SetTokenRefresher makes the client re-authenticate via fn and replay the
+// request once when a token is rejected with 401. A nil fn disables it.
+func (c *Client) SetTokenRefresher(fn TokenRefresher) {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	c.tokens.refresh = fn
+	c.tokens.replaced = make(map[string]string)
+}
+
+// CurrentToken returns the token that replaced token after a refresh, or
+// token itself if it has not been refreshed.
+func (c *Client) CurrentToken(token string) string {
+	if token == "" {
+		return token
+	}
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	for {
+		next, ok := c.tokens.replaced[token]
+		if !ok {
+			return token
+		}
+		token = next
+	}
+}
+
+// refreshToken replaces stale using the registered refresher.
+func (c *Client) refreshToken(ctx context.Context, stale string) (string, error) {
+	c.tokens.mu.Lock()
+	fn := c.tokens.refresh
+	c.tokens.mu.Unlock()
+	if fn == nil || stale == "" {
+		return "", errNoRefresher
+	}
+
+	c.tokens.refreshing.Lock()
+	defer c.tokens.refreshing.Unlock()
+
+	// Another request may have refreshed it while we waited
+	if current := c.CurrentToken(stale); current != stale {
+		return current, nil
+	}
+
+	fresh, err := fn(ctx, stale)
+	if err != nil {
+		return "", err
+	}
+	if fresh == "" || fresh == stale {
+		return "", fmt.Errorf("re-authentication returned no new token")
+	}
+
+	c.tokens.mu.Lock()
+	c.tokens.replaced[stale] = fresh
+	c.tokens.mu.Unlock()
+
+	if debugging() {
+		fmt.Fprintf(os.Stderr, "\033[1;33mToken rejected (401):\033[0m re-authenticated, replaying request\n")
+	}
+	return fresh, nil
+}
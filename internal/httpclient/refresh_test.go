@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendRefreshesTokenOn401(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-Auth-Token"))
+		if r.Header.Get("X-Auth-Token") != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	refreshes := 0
+	c := New(nil)
+	c.SetTokenRefresher(func(ctx context.Context, stale string) (string, error) {
+		refreshes++
+		return "fresh", nil
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Do(context.Background(), "POST", srv.URL, "stale", nil)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want 200", resp.StatusCode)
+		}
+	}
+
+	// The second call should go straight out with the refreshed token
+	want := []string{"stale", "fresh", "fresh"}
+	if len(seen) != len(want) {
+		t.Fatalf("tokens sent = %v; want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("tokens sent = %v; want %v", seen, want)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshed %d times; want 1", refreshes)
+	}
+}
@@ -0,0 +1,185 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = time.Second
+
+	// maxRetryDelay caps both the exponential backoff and any Retry-After
+	// the server asks for, so a bad header can't stall a run indefinitely.
+	maxRetryDelay = 60 * time.Second
+)
+
+// retryPolicy controls how many times an idempotent request is attempted
+// and how long to wait between attempts.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// currentRetryPolicy reads retry_max_attempts and retry_base_delay from the
+// config. retry_base_delay accepts a Go duration ("500ms", "2s") or a plain
+// number of seconds.
+func currentRetryPolicy() retryPolicy {
+	p := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+	}
+
+	if viper.IsSet("retry_max_attempts") {
+		if n := viper.GetInt("retry_max_attempts"); n > 0 {
+			p.maxAttempts = n
+		}
+	}
+	if s := strings.TrimSpace(viper.GetString("retry_base_delay")); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			p.baseDelay = d
+		} else if secs, err := strconv.ParseFloat(s, 64); err == nil && secs >= 0 {
+			p.baseDelay = time.Duration(secs * float64(time.Second))
+		}
+	}
+
+	return p
+}
+
+type retryKey struct{}
+
+// WithRetries returns a copy of ctx under which idempotent requests are
+// attempted up to maxAttempts times, backing off from baseDelay, instead of
+// following the configured policy. It is for requests that are expected to
+// hit transient conflicts for a while, such as deleting an image a volume
+// was just created from.
+func WithRetries(ctx context.Context, maxAttempts int, baseDelay time.Duration) context.Context {
+	return context.WithValue(ctx, retryKey{}, retryPolicy{maxAttempts: maxAttempts, baseDelay: baseDelay})
+}
+
+// contextRetryPolicy returns the policy set by WithRetries, or the
+// configured one.
+func contextRetryPolicy(ctx context.Context) retryPolicy {
+	if p, ok := ctx.Value(retryKey{}).(retryPolicy); ok {
+		return p
+	}
+	return currentRetryPolicy()
+}
+
+// backoff returns the delay before the given retry (1 for the first retry).
+func (p retryPolicy) backoff(retry int) time.Duration {
+	d := p.baseDelay
+	for i := 1; i < retry && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
+}
+
+// isIdempotent reports whether a request can be safely replayed.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryableStatus reports whether a response status is worth retrying.
+// 409 is included because Nova and Glance return it for transient
+// conflicts, such as deleting an image that is still being copied.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500 && code != http.StatusNotImplemented
+}
+
+// retryableError reports whether a transport error looks like a dropped
+// connection rather than a bad request or a cancelled context.
+func retryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date. It returns false if the header is absent or
+// unparseable.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryDelay picks the wait before the next attempt, preferring the
+// server's Retry-After over our own backoff.
+func (p retryPolicy) retryDelay(retry int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if d > maxRetryDelay {
+				d = maxRetryDelay
+			}
+			return d
+		}
+	}
+	return p.backoff(retry)
+}
+
+// SleepContext waits for d or until ctx is done, returning ctx.Err() if it
+// is.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// printRetry logs a retry under --debug.
+func printRetry(method, url string, attempt, maxAttempts int, reason string, delay time.Duration) {
+	if !debugging() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\033[1;33mRetry %d/%d:\033[0m %s %s (%s), waiting %s\n",
+		attempt, maxAttempts-1, method, url, reason, delay)
+}
@@ -0,0 +1,89 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSOptions configures how connections to VHI are verified.
+type TLSOptions struct {
+	CACert   string // PEM bundle trusted in addition to the system roots
+	CertFile string // client certificate for mutual TLS
+	KeyFile  string // key for CertFile
+	Insecure bool   // skip server certificate verification entirely
+}
+
+// tlsConfig is used by every request path: Default, auth requests and
+// image uploads. nil means Go's defaults.
+var tlsConfig *tls.Config
+
+// Config builds a tls.Config from the options, or nil if none are set.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	if o.CACert == "" && o.CertFile == "" && o.KeyFile == "" && !o.Insecure {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.Insecure,
+	}
+
+	if o.CACert != "" {
+		pem, err := os.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cacert: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in cacert '%s'", o.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate needs both cert and key")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// ConfigureTLS applies opts to all requests sent by this package.
+func ConfigureTLS(opts TLSOptions) error {
+	cfg, err := opts.Config()
+	if err != nil {
+		return err
+	}
+	tlsConfig = cfg
+	Default.SetTLSConfig(cfg)
+	return nil
+}
+
+// SetTLSConfig sets the TLS config of the client's transport, if it is an
+// *http.Transport.
+func (c *Client) SetTLSConfig(cfg *tls.Config) {
+	if t, ok := c.HTTP.Transport.(*http.Transport); ok {
+		t.TLSClientConfig = cfg
+	}
+}
+
+// newTransport returns a transport for one-off clients that honours the
+// configured TLS and proxy options.
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tlsConfig
+	t.Proxy = proxyFunc
+	return t
+}
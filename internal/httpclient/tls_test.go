@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigureTLSCACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(nil)
+	if _, err := c.Do(context.Background(), "HEAD", srv.URL, "", nil); err == nil {
+		t.Fatal("expected an unknown authority error without cacert")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := TLSOptions{CACert: caFile}.Config()
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	c.SetTLSConfig(cfg)
+	resp, err := c.Do(context.Background(), "HEAD", srv.URL, "", nil)
+	if err != nil {
+		t.Fatalf("request with cacert: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	if cfg, err := (TLSOptions{}).Config(); cfg != nil || err != nil {
+		t.Errorf("empty options: got %v, %v; want nil, nil", cfg, err)
+	}
+	if _, err := (TLSOptions{CertFile: "client.pem"}).Config(); err == nil {
+		t.Error("expected an error for a cert without a key")
+	}
+	if _, err := (TLSOptions{CACert: filepath.Join(t.TempDir(), "missing.pem")}).Config(); err == nil {
+		t.Error("expected an error for a missing cacert")
+	}
+	cfg, err := TLSOptions{Insecure: true}.Config()
+	if err != nil || !cfg.InsecureSkipVerify {
+		t.Errorf("insecure: got %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceRecord is one HTTP exchange in the --trace-file output.
+type TraceRecord struct {
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Attempt         int               `json:"attempt,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	LatencyMS       int64             `json:"latency_ms"`
+	RequestID       string            `json:"request_id,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     interface{}       `json:"request_body,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    interface{}       `json:"response_body,omitempty"`
+}
+
+// tracer writes trace records as JSON lines. nil disables tracing.
+var tracer *traceWriter
+
+type traceWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// OpenTrace writes a record of every request to path, appending to an
+// existing file. "-" writes to stderr.
+func OpenTrace(path string) error {
+	if path == "-" {
+		tracer = &traceWriter{w: os.Stderr}
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %v", err)
+	}
+	tracer = &traceWriter{w: f, c: f}
+	return nil
+}
+
+// CloseTrace stops tracing and closes the trace file.
+func CloseTrace() error {
+	t := tracer
+	tracer = nil
+	if t == nil || t.c == nil {
+		return nil
+	}
+	return t.c.Close()
+}
+
+func tracing() bool {
+	return tracer != nil
+}
+
+func (t *traceWriter) write(rec TraceRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(append(line, '\n'))
+}
+
+// traceExchange records one request and its response or error. Bodies are
+// redacted JSON; anything else is summarised.
+func traceExchange(start time.Time, attempt int, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, err error) {
+	if tracer == nil {
+		return
+	}
+	rec := TraceRecord{
+		Time:           start.UTC(),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Attempt:        attempt,
+		LatencyMS:      time.Since(start).Milliseconds(),
+		RequestHeaders: RedactHeaders(req.Header),
+		RequestBody:    traceBody(req.Header.Get("Content-Type"), reqBody),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if resp != nil {
+		rec.Status = resp.StatusCode
+		rec.RequestID = requestID(resp.Header)
+		rec.ResponseHeaders = RedactHeaders(resp.Header)
+		rec.ResponseBody = traceBody(resp.Header.Get("Content-Type"), respBody)
+	}
+	tracer.write(rec)
+}
+
+func traceBody(contentType string, body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if v, ok := RedactJSON(body); ok {
+		return v
+	}
+	return fmt.Sprintf("<%d bytes of %s>", len(body), contentTypeOrUnknown(contentType))
+}
+
+// requestID returns the OpenStack request ID of a response, if any.
+func requestID(h http.Header) string {
+	for _, key := range []string{"X-Openstack-Request-Id", "X-Compute-Request-Id", "X-Request-Id"} {
+		if id := h.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// isJSONContent reports whether a body of this type is worth buffering for
+// debug output and traces. Image data never is.
+func isJSONContent(contentType string) bool {
+	return strings.Contains(contentType, "json")
+}
+
+// captureBody buffers a JSON response body so it can be logged and still
+// read by the caller. Other bodies are left streaming and return nil.
+func captureBody(resp *http.Response) []byte {
+	if resp == nil || resp.Body == nil || !isJSONContent(resp.Header.Get("Content-Type")) {
+		return nil
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return data
+}
+
+func contentTypeOrUnknown(contentType string) string {
+	if contentType == "" {
+		return "unknown type"
+	}
+	return contentType
+}
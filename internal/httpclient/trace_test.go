@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSON(t *testing.T) {
+	body := `{"auth":{"identity":{"methods":["password","token"],
+		"password":{"user":{"name":"bob","password":"hunter2"}},
+		"token":{"id":"gAAAA-old"},
+		"application_credential":{"id":"cred","secret":"s3cret"}}},
+		"server":{"name":"vm1","user_data":"I2Nsb3VkLWNvbmZpZw==","adminPass":"pw"}}`
+
+	v, ok := RedactJSON([]byte(body))
+	if !ok {
+		t.Fatal("RedactJSON: not JSON")
+	}
+	out, _ := json.Marshal(v)
+	for _, secret := range []string{"hunter2", "gAAAA-old", "s3cret", "I2Nsb3VkLWNvbmZpZw==", `"pw"`} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("redacted body still contains %s: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{`"name":"bob"`, `"id":"cred"`, `"name":"vm1"`} {
+		if !strings.Contains(string(out), kept) {
+			t.Errorf("redacted body lost %s: %s", kept, out)
+		}
+	}
+
+	if _, ok := RedactJSON([]byte("not json")); ok {
+		t.Error("RedactJSON accepted a non-JSON body")
+	}
+}
+
+func TestTraceFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Subject-Token", "gAAAA-new")
+		w.Header().Set("X-Openstack-Request-Id", "req-123")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"token":{"expires_at":"2030-01-01T00:00:00Z"}}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := OpenTrace(path); err != nil {
+		t.Fatalf("OpenTrace: %v", err)
+	}
+
+	resp, err := New(nil).Do(context.Background(), "POST", srv.URL+"/v3/auth/tokens", "gAAAA-old",
+		strings.NewReader(`{"auth":{"identity":{"password":{"user":{"password":"hunter2"}}}}}`))
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	// The caller still gets the body the trace captured
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "expires_at") {
+		t.Errorf("response body lost after tracing: %q", body)
+	}
+	if err := CloseTrace(); err != nil {
+		t.Fatalf("CloseTrace: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("trace file is empty")
+	}
+	line := scanner.Text()
+	for _, secret := range []string{"hunter2", "gAAAA-old", "gAAAA-new"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("trace leaks %s: %s", secret, line)
+		}
+	}
+
+	var rec TraceRecord
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		t.Fatalf("trace line is not JSON: %v", err)
+	}
+	if rec.Method != "POST" || rec.Status != http.StatusCreated || rec.RequestID != "req-123" {
+		t.Errorf("got method %q status %d request id %q", rec.Method, rec.Status, rec.RequestID)
+	}
+}
@@ -0,0 +1,298 @@
+// Package resolver turns the resource references users type (IDs, names,
+// unique prefixes and name@project) into resource IDs.
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jessegalley/vhicmd/api"
+)
+
+// Kind names the type of resource being resolved. It is also the noun used
+// in error messages.
+type Kind string
+
+const (
+	KindVM      Kind = "VM"
+	KindImage   Kind = "image"
+	KindVolume  Kind = "volume"
+	KindNetwork Kind = "network"
+	KindPort    Kind = "port"
+	KindFlavor  Kind = "flavor"
+	KindProject Kind = "project"
+)
+
+// Candidate is a resource a reference may point at.
+type Candidate struct {
+	ID   string
+	Name string
+}
+
+// String formats a candidate as "name (id)", or just the ID when unnamed.
+func (c Candidate) String() string {
+	if c.Name == "" {
+		return c.ID
+	}
+	return fmt.Sprintf("%s (%s)", c.Name, c.ID)
+}
+
+// NotFoundError is returned when nothing matches a reference.
+type NotFoundError struct {
+	Kind Kind
+	Ref  string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no %s found matching %q", e.Kind, e.Ref)
+}
+
+// AmbiguousError is returned when a reference matches more than one
+// resource. Candidates lists every match so the user can pick an ID.
+type AmbiguousError struct {
+	Kind       Kind
+	Ref        string
+	Candidates []Candidate
+}
+
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d %ss; use an ID or a more specific name:", e.Ref, len(e.Candidates), e.Kind)
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %s", c)
+	}
+	return b.String()
+}
+
+// Match picks the candidate ref refers to. An exact ID wins, then an exact
+// name, then a unique prefix of an ID or name. More than one exact name, or
+// more than one prefix match, is an AmbiguousError; there is never a fallback
+// to the raw string.
+func Match(kind Kind, ref string, candidates []Candidate) (Candidate, error) {
+	if ref == "" {
+		return Candidate{}, &NotFoundError{Kind: kind, Ref: ref}
+	}
+
+	for _, c := range candidates {
+		if c.ID == ref {
+			return c, nil
+		}
+	}
+
+	var named []Candidate
+	for _, c := range candidates {
+		if c.Name == ref {
+			named = append(named, c)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], nil
+	}
+	if len(named) > 1 {
+		return Candidate{}, ambiguous(kind, ref, named)
+	}
+
+	var prefixed []Candidate
+	for _, c := range candidates {
+		if strings.HasPrefix(c.ID, ref) || strings.HasPrefix(c.Name, ref) {
+			prefixed = append(prefixed, c)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0], nil
+	}
+	if len(prefixed) > 1 {
+		return Candidate{}, ambiguous(kind, ref, prefixed)
+	}
+
+	return Candidate{}, &NotFoundError{Kind: kind, Ref: ref}
+}
+
+func ambiguous(kind Kind, ref string, matches []Candidate) *AmbiguousError {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Name != matches[j].Name {
+			return matches[i].Name < matches[j].Name
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return &AmbiguousError{Kind: kind, Ref: ref, Candidates: matches}
+}
+
+// SplitProject splits "name@project" into its parts. The last "@" separates
+// the project, so a name containing "@" can still be qualified. Resolve
+// tries the whole reference as a name before reading the suffix as a
+// project.
+func SplitProject(ref string) (name, project string) {
+	i := strings.LastIndex(ref, "@")
+	if i <= 0 || i == len(ref)-1 {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// Resolver resolves references against the APIs reachable with a token.
+type Resolver struct {
+	client    *api.Client
+	projectID string
+	project   string
+}
+
+// New returns a Resolver for the project tok is scoped to.
+func New(client *api.Client, tok api.Token) *Resolver {
+	return &Resolver{client: client, projectID: tok.ProjectID, project: tok.Project}
+}
+
+// ID resolves ref and returns only the ID.
+func (r *Resolver) ID(ctx context.Context, kind Kind, ref string) (string, error) {
+	c, err := r.Resolve(ctx, kind, ref)
+	return c.ID, err
+}
+
+// Resolve resolves ref to a single resource of the given kind. A full UUID
+// is taken as given; anything else is matched against a listing of the
+// resources visible in the token's project, or in the named project for
+// name@project unless a resource is named exactly ref.
+func (r *Resolver) Resolve(ctx context.Context, kind Kind, ref string) (Candidate, error) {
+	name, project := SplitProject(ref)
+
+	if isUUID(name) {
+		return Candidate{ID: name}, nil
+	}
+
+	projectID := ""
+	if project != "" {
+		// A name may itself contain "@"; an exact match on the whole
+		// reference wins over reading the suffix as a project.
+		local, err := r.list(ctx, kind, "")
+		if err != nil {
+			return Candidate{}, fmt.Errorf("failed to resolve %s %q: %v", kind, ref, err)
+		}
+		var named []Candidate
+		for _, c := range local {
+			if c.Name == ref {
+				named = append(named, c)
+			}
+		}
+		if len(named) == 1 {
+			return named[0], nil
+		}
+		if len(named) > 1 {
+			return Candidate{}, ambiguous(kind, ref, named)
+		}
+
+		if kind == KindFlavor || kind == KindProject {
+			return Candidate{}, fmt.Errorf("%ss are not project-scoped; drop the @%s", kind, project)
+		}
+		id, err := r.resolveProject(ctx, project)
+		if err != nil {
+			return Candidate{}, err
+		}
+		projectID = id
+	}
+
+	candidates, err := r.list(ctx, kind, projectID)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("failed to resolve %s %q: %v", kind, ref, err)
+	}
+
+	c, err := Match(kind, name, candidates)
+	if err != nil {
+		// Report the reference as typed, including any @project.
+		switch e := err.(type) {
+		case *NotFoundError:
+			e.Ref = ref
+		case *AmbiguousError:
+			e.Ref = ref
+		}
+		return Candidate{}, err
+	}
+	return c, nil
+}
+
+// resolveProject turns a project name or ID into an ID, without a lookup
+// when it names the token's own project.
+func (r *Resolver) resolveProject(ctx context.Context, ref string) (string, error) {
+	if ref == r.projectID || (r.project != "" && strings.EqualFold(ref, r.project)) {
+		return r.projectID, nil
+	}
+	c, err := r.Resolve(ctx, KindProject, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// list fetches every resource of kind, limited to projectID when set.
+// Listing another project's VMs or volumes needs an admin token.
+func (r *Resolver) list(ctx context.Context, kind Kind, projectID string) ([]Candidate, error) {
+	params := map[string]string{}
+	otherProject := projectID != "" && projectID != r.projectID
+
+	switch kind {
+	case KindVM:
+		if otherProject {
+			params["all_tenants"] = "1"
+			params["project_id"] = projectID
+		}
+		items, err := r.client.Compute().ServerPager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(v api.VM) Candidate { return Candidate{v.ID, v.Name} })
+	case KindImage:
+		if projectID != "" {
+			params["owner"] = projectID
+		}
+		items, err := r.client.Image().ImagePager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(i api.Image) Candidate { return Candidate{i.ID, i.Name} })
+	case KindVolume:
+		if otherProject {
+			params["all_tenants"] = "1"
+			params["project_id"] = projectID
+		}
+		items, err := r.client.Volume().VolumePager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(v api.Volume) Candidate { return Candidate{v.ID, v.Name} })
+	case KindNetwork:
+		if projectID != "" {
+			params["project_id"] = projectID
+		}
+		items, err := r.client.Network().NetworkPager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(n api.Network) Candidate { return Candidate{n.ID, n.Name} })
+	case KindPort:
+		if projectID != "" {
+			params["project_id"] = projectID
+		}
+		items, err := r.client.Network().PortPager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(p api.Port) Candidate { return Candidate{p.ID, p.Name} })
+	case KindFlavor:
+		items, err := r.client.Compute().FlavorPager(params, api.DefaultPageSize).All(ctx)
+		return candidates(items, err, func(f api.Flavor) Candidate { return Candidate{f.ID, f.Name} })
+	case KindProject:
+		resp, err := r.client.Identity().ListProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var out []Candidate
+		for _, p := range resp.Projects {
+			out = append(out, Candidate{p.ID, p.Name})
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown resource kind %q", kind)
+}
+
+func candidates[T any](items []T, err error, conv func(T) Candidate) ([]Candidate, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Candidate, 0, len(items))
+	for _, item := range items {
+		out = append(out, conv(item))
+	}
+	return out, nil
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
@@ -0,0 +1,81 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	candidates := []Candidate{
+		{ID: "1a2b3c", Name: "web-1"},
+		{ID: "4d5e6f", Name: "web-10"},
+		{ID: "7a8b9c", Name: "db"},
+		{ID: "0f0f0f", Name: "db"},
+		{ID: "abcdef", Name: "cache"},
+	}
+
+	tests := []struct {
+		name      string
+		ref       string
+		wantID    string
+		ambiguous int
+		notFound  bool
+	}{
+		{name: "exact ID", ref: "7a8b9c", wantID: "7a8b9c"},
+		{name: "exact name beats longer names", ref: "web-1", wantID: "1a2b3c"},
+		{name: "unique name prefix", ref: "cach", wantID: "abcdef"},
+		{name: "unique ID prefix", ref: "4d5", wantID: "4d5e6f"},
+		{name: "duplicate names", ref: "db", ambiguous: 2},
+		{name: "shared prefix", ref: "web", ambiguous: 2},
+		{name: "ID prefix with no name match", ref: "a", wantID: "abcdef"},
+		{name: "typo", ref: "wbe-1", notFound: true},
+		{name: "empty", ref: "", notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(KindVM, tt.ref, candidates)
+
+			var amb *AmbiguousError
+			var nf *NotFoundError
+			switch {
+			case tt.notFound:
+				if !errors.As(err, &nf) {
+					t.Fatalf("expected NotFoundError, got %v", err)
+				}
+			case tt.ambiguous > 1:
+				if !errors.As(err, &amb) {
+					t.Fatalf("expected AmbiguousError, got %v", err)
+				}
+				if len(amb.Candidates) != tt.ambiguous {
+					t.Errorf("got %d candidates, want %d", len(amb.Candidates), tt.ambiguous)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.ID != tt.wantID {
+					t.Errorf("got %s, want %s", got.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitProject(t *testing.T) {
+	tests := []struct {
+		ref, name, project string
+	}{
+		{"web-1", "web-1", ""},
+		{"web-1@prod", "web-1", "prod"},
+		{"a@b@prod", "a@b", "prod"},
+		{"@prod", "@prod", ""},
+		{"web-1@", "web-1@", ""},
+	}
+	for _, tt := range tests {
+		name, project := SplitProject(tt.ref)
+		if name != tt.name || project != tt.project {
+			t.Errorf("SplitProject(%q) = %q, %q; want %q, %q", tt.ref, name, project, tt.name, tt.project)
+		}
+	}
+}
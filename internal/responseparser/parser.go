@@ -3,7 +3,9 @@ package responseparser
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/gookit/color"
 	"github.com/olekukonko/tablewriter"
@@ -494,8 +496,18 @@ type VM struct {
 	Name       string
 	PowerState string
 	IPs        string
+
+	// Optional columns, only filled in when requested
+	Flavor  string `json:",omitempty"`
+	Created string `json:",omitempty"`
+	Host    string `json:",omitempty"`
+	Project string `json:",omitempty"`
 }
 
+// VMColumns are the optional columns PrintVMsTable can show after the
+// default ones.
+var VMColumns = []string{"flavor", "created", "host", "project"}
+
 type VMDetails struct {
 	ID             string
 	Name           string
@@ -510,6 +522,7 @@ type VMDetails struct {
 	Volumes        []VolumeDetail
 	Flavor         FlavorDetail
 	Metadata       map[string]string
+	Tags           []string
 }
 
 type FlavorDetail struct {
@@ -559,19 +572,38 @@ type SecurityGroupRule struct {
 	EtherType      string
 }
 
-func PrintVMsTable(vms []VM) {
+// PrintVMsTable prints vms with the default columns followed by the
+// requested optional columns (see VMColumns), in the order given.
+func PrintVMsTable(vms []VM, columns ...string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"NAME", "ID", "STATE", "IPs"})
+	header := []string{"NAME", "ID", "STATE", "IPs"}
+	for _, c := range columns {
+		header = append(header, strings.ToUpper(c))
+	}
+	table.SetHeader(header)
 
 	applyTableStyle(table)
 
 	for _, vm := range vms {
-		table.Append([]string{
+		row := []string{
 			color.Style{color.FgGreen}.Render(vm.Name),
 			vm.ID,
 			colorStyleStatus(vm.PowerState),
 			stringOrNA(vm.IPs),
-		})
+		}
+		for _, c := range columns {
+			switch c {
+			case "flavor":
+				row = append(row, stringOrNA(vm.Flavor))
+			case "created":
+				row = append(row, stringOrNA(vm.Created))
+			case "host":
+				row = append(row, stringOrNA(vm.Host))
+			case "project":
+				row = append(row, stringOrNA(vm.Project))
+			}
+		}
+		table.Append(row)
 	}
 	table.Render()
 }
@@ -752,6 +784,19 @@ func PrintVMDetailsTable(details []VMDetails) {
 		}
 		metaTable.Render()
 	}
+
+	// Display Tags
+	if len(d.Tags) > 0 {
+		fmt.Println("\nTags:")
+		tagTable := tablewriter.NewWriter(os.Stdout)
+		tagTable.SetHeader([]string{"Tag"})
+		applyTableStyle(tagTable)
+
+		for _, tag := range d.Tags {
+			tagTable.Append([]string{tag})
+		}
+		tagTable.Render()
+	}
 }
 
 // -------------------------------------------------------------------
@@ -878,16 +923,22 @@ type CatalogEntry struct {
 	Interface string
 	Region    string
 	URL       string
+	Selected  bool
 }
 
 func PrintCatalogTable(entries []CatalogEntry) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"NAME", "TYPE", "INTERFACE", "REGION", "URL"})
+	table.SetHeader([]string{"SELECTED", "NAME", "TYPE", "INTERFACE", "REGION", "URL"})
 
 	applyTableStyle(table)
 
 	for _, e := range entries {
+		selected := ""
+		if e.Selected {
+			selected = color.Style{color.FgGreen, color.OpBold}.Render("*")
+		}
 		table.Append([]string{
+			selected,
 			color.Style{color.FgGreen}.Render(e.Name),
 			e.Type,
 			e.Interface,
@@ -897,3 +948,182 @@ func PrintCatalogTable(entries []CatalogEntry) {
 	}
 	table.Render()
 }
+
+// -------------------------------------------------------------------
+// TOKENS
+// -------------------------------------------------------------------
+
+type TokenEntry struct {
+	Host      string    `json:"host"`
+	Domain    string    `json:"domain"`
+	Project   string    `json:"project"`
+	ProjectID string    `json:"project_id,omitempty"`
+	Scope     string    `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func PrintTokensTable(entries []TokenEntry) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"HOST", "DOMAIN", "PROJECT", "SCOPE", "EXPIRES", "STATUS"})
+
+	applyTableStyle(table)
+
+	for _, e := range entries {
+		status := color.Style{color.FgGreen, color.OpBold}.Render("VALID")
+		if time.Now().After(e.ExpiresAt) {
+			status = color.Style{color.FgRed, color.OpBold}.Render("EXPIRED")
+		}
+		table.Append([]string{
+			color.Style{color.FgGreen}.Render(e.Host),
+			stringOrNA(e.Domain),
+			stringOrNA(e.Project),
+			e.Scope,
+			e.ExpiresAt.Local().Format("2006-01-02 15:04:05"),
+			status,
+		})
+	}
+	table.Render()
+}
+
+// -------------------------------------------------------------------
+// CONTEXTS
+// -------------------------------------------------------------------
+
+type ContextEntry struct {
+	Current  bool   `json:"current"`
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Domain   string `json:"domain"`
+	Project  string `json:"project"`
+	Username string `json:"username"`
+}
+
+func PrintContextsTable(entries []ContextEntry) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"CURRENT", "NAME", "HOST", "DOMAIN", "PROJECT", "USERNAME"})
+
+	applyTableStyle(table)
+
+	for _, e := range entries {
+		current := ""
+		if e.Current {
+			current = color.Style{color.FgGreen, color.OpBold}.Render("*")
+		}
+		table.Append([]string{
+			current,
+			color.Style{color.FgGreen}.Render(e.Name),
+			stringOrNA(e.Host),
+			stringOrNA(e.Domain),
+			stringOrNA(e.Project),
+			stringOrNA(e.Username),
+		})
+	}
+	table.Render()
+}
+
+// -------------------------------------------------------------------
+// BULK OPERATIONS
+// -------------------------------------------------------------------
+
+// BulkResult is the outcome of one item of a bulk operation.
+type BulkResult struct {
+	Name   string
+	ID     string
+	Result string // "ok", "failed" or "skipped"
+	Error  string
+}
+
+// PrintBulkResultsTable prints a summary of a bulk operation.
+func PrintBulkResultsTable(results []BulkResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "ID", "RESULT", "ERROR"})
+
+	applyTableStyle(table)
+
+	for _, r := range results {
+		result := color.Style{color.FgGreen, color.OpBold}.Render(r.Result)
+		switch r.Result {
+		case "failed":
+			result = color.Style{color.FgRed, color.OpBold}.Render(r.Result)
+		case "skipped":
+			result = color.Style{color.FgYellow, color.OpBold}.Render(r.Result)
+		}
+		table.Append([]string{
+			color.Style{color.FgGreen}.Render(stringOrNA(r.Name)),
+			r.ID,
+			result,
+			r.Error,
+		})
+	}
+	table.Render()
+}
+
+// DeleteItem is a resource removed, or to be removed, along with a VM.
+type DeleteItem struct {
+	Type   string // "vm", "volume" or "port"
+	Name   string
+	ID     string
+	Action string
+}
+
+// PrintDeleteItemsTable prints what a VM deletion does, or did, to the VM
+// and its attached resources.
+func PrintDeleteItemsTable(items []DeleteItem) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"TYPE", "NAME", "ID", "ACTION"})
+
+	applyTableStyle(table)
+
+	for _, item := range items {
+		action := item.Action
+		switch {
+		case strings.HasPrefix(action, "delete"):
+			action = color.Style{color.FgRed, color.OpBold}.Render(action)
+		case strings.HasPrefix(action, "failed"):
+			action = color.Style{color.FgRed}.Render(action)
+		default:
+			action = color.Style{color.FgYellow}.Render(action)
+		}
+		table.Append([]string{
+			item.Type,
+			color.Style{color.FgGreen}.Render(stringOrNA(item.Name)),
+			item.ID,
+			action,
+		})
+	}
+	table.Render()
+}
+
+// -------------------------------------------------------------------
+// SNAPSHOTS
+// -------------------------------------------------------------------
+
+// VMSnapshot is a VM snapshot: an image and, for a volume-backed VM, the
+// volume snapshots it refers to.
+type VMSnapshot struct {
+	Name            string
+	ID              string
+	Status          string
+	Created         string
+	Size            int64
+	VolumeSnapshots int
+}
+
+func PrintVMSnapshotsTable(snapshots []VMSnapshot) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "ID", "STATUS", "CREATED", "SIZE (B)", "VOLUME SNAPSHOTS"})
+
+	applyTableStyle(table)
+
+	for _, s := range snapshots {
+		table.Append([]string{
+			color.Style{color.FgGreen}.Render(s.Name),
+			s.ID,
+			colorStyleStatus(strings.ToUpper(s.Status)),
+			stringOrNA(s.Created),
+			fmt.Sprintf("%d", s.Size),
+			fmt.Sprintf("%d", s.VolumeSnapshots),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,126 @@
+// Package secrets encrypts config secrets and the token file at rest with a
+// passphrase-derived key, and fetches secrets from external credential
+// helpers.
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Prefix marks an encrypted value: "enc:v1:" followed by base64 of
+// salt || nonce || AES-256-GCM ciphertext.
+const Prefix = "enc:v1:"
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 210000
+)
+
+// IsEncrypted reports whether s holds a value produced by Encrypt.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), Prefix)
+}
+
+// Encrypt seals plaintext with a key derived from passphrase.
+func Encrypt(plaintext []byte, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("empty passphrase")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, plaintext, []byte(Prefix))
+	return Prefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt opens a value produced by Encrypt.
+func Decrypt(value, passphrase string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if !IsEncrypted(value) {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted value: %v", err)
+	}
+	if len(raw) < saltSize {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+
+	salt := raw[:saltSize]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := raw[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed; wrong passphrase?")
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2SHA256([]byte(passphrase), salt, iterations, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %v", err)
+	}
+	return gcm, nil
+}
+
+// FromHelper runs a credential helper command (git-credential style) and
+// returns the secret it prints. The helper is run through the shell as
+// "<helper> get <key>", with the key also in VHICMD_SECRET_KEY.
+func FromHelper(helper, key string) (string, error) {
+	cmd := exec.Command("sh", "-c", helper+" get "+key)
+	cmd.Env = append(os.Environ(), "VHICMD_SECRET_KEY="+key)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper failed for '%s': %v", key, err)
+	}
+
+	secret := strings.TrimRight(stdout.String(), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("credential helper returned no value for '%s'", key)
+	}
+	return secret, nil
+}